@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Hex colors in the #rrggbb or #rgb form, but not #rrggbbaa or #rgba.
+var hexColorRegex = regexp.MustCompile(`#(?:[0-9a-fA-F]{6}|[0-9a-fA-F]{3})\b`)
+
+// Replacement formats of hex codes for batch -rewrite.
+var rewriteFormats = map[string]string{
+	"cterm":   "%d",
+	"ansi-fg": `\033[38;5;%dm`,
+	"ansi-bg": `\033[48;5;%dm`,
+}
+
+func printBatchHelpAndExit(fs *flag.FlagSet) {
+	fmt.Printf("Usage: %s [options] batch [batch options] [<file>]\n", filepath.Base(os.Args[0]))
+	fmt.Println("Maps every hex color (#rrggbb or #rgb) found in the file (stdin by default), e.g. CSS or base46 theme,")
+	fmt.Println("to the closest palette color and prints the mapping table.")
+	fmt.Println("With -rewrite prints the input with hex colors replaced, the mapping table goes to stderr.")
+	fmt.Println("Batch options:")
+	fs.PrintDefaults()
+	os.Exit(1)
+}
+
+// batchMain runs the batch subcommand.
+func batchMain(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	rewrite := fs.String("rewrite", "", "replace hex colors with `format`: cterm (palette index), ansi-fg or ansi-bg (escape sequences)")
+	inPlace := fs.Bool("w", false, "write the rewritten input back to the file instead of stdout")
+	fs.Usage = func() { printBatchHelpAndExit(fs) }
+	fs.Parse(args)
+
+	if fs.NArg() > 1 || *inPlace && (*rewrite == "" || fs.NArg() == 0) {
+		printBatchHelpAndExit(fs)
+	}
+	format, ok := rewriteFormats[*rewrite]
+	if *rewrite != "" && !ok {
+		fmt.Printf("ERROR: unknown rewrite format %q\n\n", *rewrite)
+		printBatchHelpAndExit(fs)
+	}
+
+	var input []byte
+	var err error
+	if fs.NArg() == 1 {
+		input, err = os.ReadFile(fs.Arg(0))
+	} else {
+		input, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mapping, err := mapHexColors(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *rewrite == "" {
+		printMapping(os.Stdout, input, mapping)
+		return
+	}
+	printMapping(os.Stderr, input, mapping)
+	output := hexColorRegex.ReplaceAllFunc(input, func(hex []byte) []byte {
+		return []byte(fmt.Sprintf(format, mapping[strings.ToLower(string(hex))].Color.Id))
+	})
+	if *inPlace {
+		info, err := os.Stat(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = os.WriteFile(fs.Arg(0), output, info.Mode())
+	} else {
+		_, err = os.Stdout.Write(output)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// mapHexColors finds the closest palette color for every distinct hex color of the input.
+func mapHexColors(input []byte) (map[string]result, error) {
+	index, err := newColorIndex(colors, *metricName)
+	if err != nil {
+		return nil, err
+	}
+	mapping := make(map[string]result)
+	for _, match := range hexColorRegex.FindAll(input, -1) {
+		hex := strings.ToLower(string(match))
+		if _, ok := mapping[hex]; ok {
+			continue
+		}
+		src, err := parseColor(hex, colors)
+		if err != nil {
+			return nil, err
+		}
+		mapping[hex] = index.Nearest(src, 1)[0]
+	}
+	return mapping, nil
+}
+
+// printMapping prints the mapping table in order of the first appearance of colors in the input.
+func printMapping(w io.Writer, input []byte, mapping map[string]result) {
+	printed := make(map[string]bool)
+	for _, match := range hexColorRegex.FindAll(input, -1) {
+		hex := strings.ToLower(string(match))
+		if printed[hex] {
+			continue
+		}
+		printed[hex] = true
+		result := mapping[hex]
+		fmt.Fprintf(w, "%s -> %d (%s: %s) (%s distance %.4g)\n", hex, result.Color.Id, result.Color.Hex, result.Color.Name, *metricName, result.Distance)
+	}
+}
@@ -1,27 +1,33 @@
 /*
 closest-term-colors outputs `n` (default 1) term color codes closest to the color specified via
 command-line argument.
+
+The xterm 256-color table is bundled into the binary (see colors.json, generated by gen-colors.go),
+so no network access is needed. Other palettes can be chosen with -palette: the 16-color ANSI palette,
+the 88-color rxvt palette or the user's terminal theme (Xresources, kitty, Alacritty or base46).
+A color table in the JSON format can also be given with -palette-file or -palette-url.
 */
 package main
 
+//go:generate sh -c "go run gen-colors.go > colors.json"
+
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
-
-	"github.com/andbar-ru/closest_colors"
-)
-
-const (
-	colorsUrl = "https://jonasjacek.github.io/colors/data.json"
 )
 
 var (
+	paletteSpec = flag.String("palette", defaultPalette, "`palette` to search in: "+paletteNames())
+	paletteFile = flag.String("palette-file", "", "read color table from `file` instead of the bundled one")
+	paletteUrl  = flag.String("palette-url", "", "fetch color table from `url` instead of using the bundled one, e.g. https://jonasjacek.github.io/colors/data.json")
+	preview     = flag.Bool("preview", false, "print the source color and the results as swatches and text samples instead of the list")
+	metricName  = flag.String("metric", defaultMetric, "distance `metric`: "+metricNames())
+
 	colors                []color
 	colorRegex            = regexp.MustCompile(`(?i)^#([0-9a-f]{2})([0-9a-f]{2})([0-9a-f]{2})$`)
 	numberRegex           = regexp.MustCompile(`^\d+$`)
@@ -49,46 +55,60 @@ func check(err error) {
 }
 
 func printHelpAndExit() {
-	fmt.Printf("Usage: %s <color> [<number of closest colors to print>]\n", filepath.Base(os.Args[0]))
-	fmt.Println("color must be given in hex format, e.g. '#131723'")
+	fmt.Printf("Usage: %s [options] <color> [<number of closest colors to print>]\n", filepath.Base(os.Args[0]))
+	fmt.Printf("       %s [options] batch [batch options] [<file>]\n", filepath.Base(os.Args[0]))
+	fmt.Printf("       %s [options] lookup <index or name>\n", filepath.Base(os.Args[0]))
+	fmt.Printf("       %s [options] serve [serve options]\n", filepath.Base(os.Args[0]))
+	fmt.Printf("       %s [options] image [image options] <file>\n", filepath.Base(os.Args[0]))
+	fmt.Println("color can be given as '#131723', '#123', 'rgb(19, 23, 35)', 'hsl(225, 30%, 11%)', CSS color name or palette index")
 	fmt.Printf("number of closest colors is optional (1 by default), must be integer and less than number of available colors (%d).\n", len(colors))
+	fmt.Println("Options:")
+	flag.PrintDefaults()
 	os.Exit(1)
 }
 
 func fillColors() {
-	response, err := http.Get(colorsUrl)
-	check(err)
-	defer response.Body.Close()
-	if response.StatusCode != 200 {
-		log.Panicf("%s: status code error: %d %s", colorsUrl, response.StatusCode, response.Status)
+	explicit := 0
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "palette" || f.Name == "palette-file" || f.Name == "palette-url" {
+			explicit++
+		}
+	})
+	if explicit > 1 {
+		log.Fatal("-palette, -palette-file and -palette-url are mutually exclusive")
 	}
-	err = json.NewDecoder(response.Body).Decode(&colors)
-	check(err)
-	if len(colors) != 256 {
-		log.Panicf("Number of colors must be 256, but got %d", len(colors))
+
+	var err error
+	switch {
+	case *paletteUrl != "":
+		colors, err = fetchPalette(*paletteUrl)
+	case *paletteFile != "":
+		colors, err = loadPalette("file:" + *paletteFile)
+	default:
+		colors, err = loadPalette(*paletteSpec)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 }
 
+// xtermIndexed reports whether color ids are xterm 256-color indices, i.e. the bundled xterm256 palette is used.
+func xtermIndexed() bool {
+	return *paletteSpec == "xterm256" && *paletteFile == "" && *paletteUrl == ""
+}
+
 func validateArgs() {
-	args := os.Args[1:]
+	args := flag.Args()
 	if len(args) < 1 || len(args) > 2 {
 		printHelpAndExit()
 	}
 
-	colorArg := args[0]
-	if !colorRegex.MatchString(colorArg) {
-		fmt.Printf("ERROR: color does not match regexp %s\n\n", colorRegex)
+	var err error
+	srcRgb, err = parseColor(args[0], colors)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n\n", err)
 		printHelpAndExit()
 	}
-	submatches := colorRegex.FindStringSubmatch(colorArg)
-	red, err := strconv.ParseUint(submatches[1], 16, 8)
-	check(err)
-	green, err := strconv.ParseUint(submatches[2], 16, 8)
-	check(err)
-	blue, err := strconv.ParseUint(submatches[3], 16, 8)
-	check(err)
-
-	srcRgb = rgb{Red: uint8(red), Green: uint8(green), Blue: uint8(blue)}
 
 	if len(args) == 2 {
 		numberArg := args[1]
@@ -114,22 +134,45 @@ func (c color) RGB() (uint8, uint8, uint8) {
 	return c.Rgb.Red, c.Rgb.Green, c.Rgb.Blue
 }
 
-func init() {
-	fillColors()
-}
-
 func main() {
+	flag.Usage = printHelpAndExit
+	flag.Parse()
+	fillColors()
+	if _, ok := metrics[*metricName]; !ok {
+		fmt.Printf("ERROR: unknown metric %q, must be one of: %s\n\n", *metricName, metricNames())
+		printHelpAndExit()
+	}
+	switch flag.Arg(0) {
+	case "batch":
+		batchMain(flag.Args()[1:])
+		return
+	case "lookup":
+		lookupMain(flag.Args()[1:])
+		return
+	case "serve":
+		serveMain(flag.Args()[1:])
+		return
+	case "image":
+		imageMain(flag.Args()[1:])
+		return
+	}
 	validateArgs()
 
-	rgbColors := make([]closest_colors.RGBColor, len(colors))
-	for i, c := range colors {
-		rgbColors[i] = c
+	results, err := findClosestColors(srcRgb, numberOfClosestColors, colors, *metricName)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n\n", err)
+		printHelpAndExit()
+	}
+	if *preview {
+		printPreview(os.Stdout, srcRgb, results, xtermIndexed())
+		return
 	}
-
-	results, err := closest_colors.FindClosestRGBColors(srcRgb, numberOfClosestColors, rgbColors)
-	check(err)
 	for _, result := range results {
-		resultColor := result.Color.(color)
-		fmt.Printf("%d (%s: %s) (distance %.2f)\n", resultColor.Id, resultColor.Hex, resultColor.Name, result.Distance)
+		resultColor := result.Color
+		if *metricName == defaultMetric {
+			fmt.Printf("%d (%s: %s) (distance %.2f)\n", resultColor.Id, resultColor.Hex, resultColor.Name, result.Distance)
+		} else {
+			fmt.Printf("%d (%s: %s) (%s distance %.4g)\n", resultColor.Id, resultColor.Hex, resultColor.Name, *metricName, result.Distance)
+		}
 	}
 }
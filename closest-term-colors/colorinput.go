@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	shortColorRegex = regexp.MustCompile(`(?i)^#([0-9a-f])([0-9a-f])([0-9a-f])$`)
+	functionRegex   = regexp.MustCompile(`(?i)^(rgb|hsl)a?\(\s*([^,\s]+)\s*[,\s]\s*([^,\s]+)\s*[,\s]\s*([^,\s)]+)\s*(?:[,/]\s*[^)]*)?\)$`)
+)
+
+// parseColor parses color given as #rrggbb, #rgb, rgb(r, g, b), hsl(h, s%, l%), CSS color name
+// or index of a color in palette.
+func parseColor(s string, palette []color) (rgb, error) {
+	s = strings.TrimSpace(s)
+	if colorRegex.MatchString(s) {
+		return parseHex(s)
+	}
+	if m := shortColorRegex.FindStringSubmatch(s); m != nil {
+		return parseHex("#" + m[1] + m[1] + m[2] + m[2] + m[3] + m[3])
+	}
+	if m := functionRegex.FindStringSubmatch(s); m != nil {
+		if strings.EqualFold(m[1], "rgb") {
+			return parseRgbFunction(m[2:])
+		}
+		return parseHslFunction(m[2:])
+	}
+	if hex, ok := cssColors[strings.ToLower(s)]; ok {
+		return parseHex(hex)
+	}
+	if id, err := strconv.Atoi(s); err == nil {
+		for _, c := range palette {
+			if c.Id == id {
+				return c.Rgb, nil
+			}
+		}
+		return rgb{}, fmt.Errorf("no color with index %d in the palette", id)
+	}
+	return rgb{}, fmt.Errorf("unknown color %q, must be #rrggbb, #rgb, rgb(r, g, b), hsl(h, s%%, l%%), CSS color name or palette index", s)
+}
+
+func parseRgbFunction(args []string) (rgb, error) {
+	var components [3]uint8
+	for i, arg := range args {
+		scale := 1.0
+		if value, ok := strings.CutSuffix(arg, "%"); ok {
+			arg, scale = value, 2.55
+		}
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil || value*scale < 0 || value*scale > 255 {
+			return rgb{}, fmt.Errorf("invalid rgb() component %q", args[i])
+		}
+		components[i] = uint8(math.Round(value * scale))
+	}
+	return rgb{components[0], components[1], components[2]}, nil
+}
+
+func parseHslFunction(args []string) (rgb, error) {
+	h, err := strconv.ParseFloat(strings.TrimSuffix(args[0], "deg"), 64)
+	if err != nil {
+		return rgb{}, fmt.Errorf("invalid hsl() hue %q", args[0])
+	}
+	var sl [2]float64
+	for i, arg := range args[1:] {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil || value < 0 || value > 100 {
+			return rgb{}, fmt.Errorf("invalid hsl() component %q", arg)
+		}
+		sl[i] = value / 100
+	}
+	return hslToRgb(h, sl[0], sl[1]), nil
+}
+
+// hslToRgb converts hue in degrees, saturation and lightness in range [0, 1] to RGB.
+func hslToRgb(h, s, l float64) rgb {
+	h = math.Mod(math.Mod(h, 360)+360, 360)
+	f := func(n float64) uint8 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return uint8(math.Round(255 * (l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1)))))
+	}
+	return rgb{f(0), f(8), f(4)}
+}
+
+// hsl returns hue in degrees, saturation and lightness in range [0, 1].
+func (c rgb) hsl() (float64, float64, float64) {
+	r, g, b := float64(c.Red)/255, float64(c.Green)/255, float64(c.Blue)/255
+	maxValue, minValue := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l := (maxValue + minValue) / 2
+	d := maxValue - minValue
+	if d == 0 {
+		return 0, 0, l
+	}
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch maxValue {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// nearestCssColor returns the CSS color name closest to c according to the named metric.
+func nearestCssColor(c rgb, metricName string) (string, string, float64, error) {
+	distance, ok := metrics[metricName]
+	if !ok {
+		return "", "", 0, fmt.Errorf("unknown metric %q, must be one of: %s", metricName, metricNames())
+	}
+	names := make([]string, 0, len(cssColors))
+	for name := range cssColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bestName, bestDistance := "", math.Inf(1)
+	for _, name := range names {
+		css, _ := parseHex(cssColors[name])
+		if d := distance(c, css); d < bestDistance {
+			bestName, bestDistance = name, d
+		}
+	}
+	return bestName, cssColors[bestName], bestDistance, nil
+}
+
+func printLookupHelpAndExit() {
+	fmt.Printf("Usage: %s [options] lookup <index or name>\n", filepath.Base(os.Args[0]))
+	fmt.Println("Prints hex, RGB, HSL and the nearest CSS color name of the palette color given by index or name.")
+	os.Exit(1)
+}
+
+// lookupMain runs the lookup subcommand: reverse lookup of palette colors by index or name.
+func lookupMain(args []string) {
+	if len(args) != 1 {
+		printLookupHelpAndExit()
+	}
+	var found []color
+	id, err := strconv.Atoi(args[0])
+	for _, c := range colors {
+		if err == nil && c.Id == id || err != nil && strings.EqualFold(c.Name, args[0]) {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 0 {
+		fmt.Printf("ERROR: no color %q in the palette\n\n", args[0])
+		printLookupHelpAndExit()
+	}
+
+	for i, c := range found {
+		if i > 0 {
+			fmt.Println()
+		}
+		h, s, l := c.Rgb.hsl()
+		cssName, cssHex, distance, err := nearestCssColor(c.Rgb, *metricName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d (%s: %s)\n", c.Id, c.Hex, c.Name)
+		fmt.Printf("RGB: rgb(%d, %d, %d)\n", c.Rgb.Red, c.Rgb.Green, c.Rgb.Blue)
+		fmt.Printf("HSL: hsl(%.0f, %.0f%%, %.0f%%)\n", h, s*100, l*100)
+		fmt.Printf("Nearest CSS color: %s (%s) (%s distance %.4g)\n", cssName, cssHex, *metricName, distance)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+const defaultWidth = 80
+
+func printImageHelpAndExit(fs *flag.FlagSet) {
+	fmt.Printf("Usage: %s [options] image [image options] <file>\n", filepath.Base(os.Args[0]))
+	fmt.Println("Prints PNG, JPEG or GIF image as ANSI art quantized to the palette: each character cell is")
+	fmt.Println("the upper half block with two pixels, the upper one as foreground and the lower one as background.")
+	fmt.Println("Image options:")
+	fs.PrintDefaults()
+	os.Exit(1)
+}
+
+// imageMain runs the image subcommand.
+func imageMain(args []string) {
+	fs := flag.NewFlagSet("image", flag.ExitOnError)
+	width := fs.Int("width", terminalWidth(), "output width in characters, by default terminal width (or $COLUMNS, or 80 if unknown); narrower images are not enlarged")
+	dither := fs.Bool("dither", false, "use Floyd–Steinberg dithering")
+	fs.Usage = func() { printImageHelpAndExit(fs) }
+	fs.Parse(args)
+	if fs.NArg() != 1 || *width < 1 {
+		printImageHelpAndExit(fs)
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", fs.Arg(0), err)
+	}
+
+	index, err := newColorIndex(colors, *metricName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bounds := img.Bounds()
+	w := min(*width, bounds.Dx())
+	// Two pixels per character cell vertically, so pixels are roughly square.
+	h := max(1, int(math.Round(float64(bounds.Dy())*float64(w)/float64(bounds.Dx()))))
+	pixels := resize(img, w, h+h%2)
+	cells := quantize(pixels, index, *dither)
+	indexed := xtermIndexed()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for y := 0; y < len(cells); y += 2 {
+		fg, bg := -1, -1
+		for x := range cells[y] {
+			if cells[y][x].Id != fg {
+				fg = cells[y][x].Id
+				out.WriteString(fgEscape(cells[y][x], indexed))
+			}
+			if cells[y+1][x].Id != bg {
+				bg = cells[y+1][x].Id
+				out.WriteString(bgEscape(cells[y+1][x], indexed))
+			}
+			out.WriteString("▀")
+		}
+		out.WriteString(reset + "\n")
+	}
+}
+
+// terminalWidth returns width of the terminal on stdout, $COLUMNS if stdout is not a terminal or defaultWidth.
+func terminalWidth() int {
+	if columns, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && columns > 0 {
+		return columns
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return defaultWidth
+}
+
+// resize scales image to w×h pixels averaging source pixels covered by each target pixel.
+// Transparent pixels are composed over black.
+func resize(img image.Image, w, h int) [][][3]float64 {
+	bounds := img.Bounds()
+	pixels := make([][][3]float64, h)
+	for y := range pixels {
+		pixels[y] = make([][3]float64, w)
+		y0 := bounds.Min.Y + y*bounds.Dy()/h
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/h)
+		for x := range pixels[y] {
+			x0 := bounds.Min.X + x*bounds.Dx()/w
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/w)
+			var sum [3]float64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, _ := img.At(sx, sy).RGBA()
+					sum[0] += float64(r >> 8)
+					sum[1] += float64(g >> 8)
+					sum[2] += float64(b >> 8)
+				}
+			}
+			count := float64((y1 - y0) * (x1 - x0))
+			pixels[y][x] = [3]float64{sum[0] / count, sum[1] / count, sum[2] / count}
+		}
+	}
+	return pixels
+}
+
+// quantize maps pixels to palette colors. With dither the quantization error of each pixel is
+// distributed to its unprocessed neighbours (Floyd–Steinberg).
+func quantize(pixels [][][3]float64, index *colorIndex, dither bool) [][]color {
+	cells := make([][]color, len(pixels))
+	for y := range pixels {
+		cells[y] = make([]color, len(pixels[y]))
+		for x := range pixels[y] {
+			p := pixels[y][x]
+			src := rgb{clampChannel(p[0]), clampChannel(p[1]), clampChannel(p[2])}
+			closest := index.Nearest(src, 1)[0].Color
+			cells[y][x] = closest
+			if !dither {
+				continue
+			}
+			quantized := [3]float64{float64(closest.Rgb.Red), float64(closest.Rgb.Green), float64(closest.Rgb.Blue)}
+			for i := range p {
+				e := p[i] - quantized[i]
+				spread(pixels, x+1, y, i, e*7/16)
+				spread(pixels, x-1, y+1, i, e*3/16)
+				spread(pixels, x, y+1, i, e*5/16)
+				spread(pixels, x+1, y+1, i, e*1/16)
+			}
+		}
+	}
+	return cells
+}
+
+func spread(pixels [][][3]float64, x, y, channel int, e float64) {
+	if y < len(pixels) && x >= 0 && x < len(pixels[y]) {
+		pixels[y][x][channel] += e
+	}
+}
+
+func clampChannel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, v))))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type point [3]float64
+
+// embeddings map colors into spaces where the metric is Euclidean distance, such metrics can use k-d tree.
+var embeddings = map[string]func(rgb) point{
+	"rgb": func(c rgb) point {
+		return point{float64(c.Red), float64(c.Green), float64(c.Blue)}
+	},
+	"cie76": func(c rgb) point {
+		l, a, b := c.lab()
+		return point{l, a, b}
+	},
+	"oklab": func(c rgb) point {
+		l, a, b := c.oklab()
+		return point{l, a, b}
+	},
+}
+
+// colorIndex finds nearest palette colors faster than the linear scan when there are many queries.
+// For metrics that are Euclidean distance in some color space it is a k-d tree over the palette,
+// for the other ones (redmean, ciede2000) it falls back to the linear scan.
+type colorIndex struct {
+	palette  []color
+	distance metric
+	embed    func(rgb) point
+	nodes    []kdNode // nodes[0] is the root
+}
+
+type kdNode struct {
+	point       point
+	color       int // index in palette
+	axis        int
+	left, right int // indexes in nodes, -1 if none
+}
+
+func newColorIndex(palette []color, metricName string) (*colorIndex, error) {
+	distance, ok := metrics[metricName]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q, must be one of: %s", metricName, metricNames())
+	}
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("palette is empty")
+	}
+	index := &colorIndex{palette: palette, distance: distance, embed: embeddings[metricName]}
+	if index.embed == nil {
+		return index, nil
+	}
+	items := make([]int, len(palette))
+	points := make([]point, len(palette))
+	for i, c := range palette {
+		items[i] = i
+		points[i] = index.embed(c.Rgb)
+	}
+	index.nodes = make([]kdNode, 0, len(palette))
+	index.build(items, points, 0)
+	return index, nil
+}
+
+// build adds subtree of items split by median along axis cycling with depth and returns its root.
+func (index *colorIndex) build(items []int, points []point, depth int) int {
+	if len(items) == 0 {
+		return -1
+	}
+	axis := depth % 3
+	sort.Slice(items, func(i, j int) bool {
+		return points[items[i]][axis] < points[items[j]][axis]
+	})
+	median := len(items) / 2
+	node := len(index.nodes)
+	index.nodes = append(index.nodes, kdNode{point: points[items[median]], color: items[median], axis: axis})
+	left := index.build(items[:median], points, depth+1)
+	right := index.build(items[median+1:], points, depth+1)
+	index.nodes[node].left, index.nodes[node].right = left, right
+	return node
+}
+
+// Nearest returns k palette colors closest to src in increasing distance order.
+func (index *colorIndex) Nearest(src rgb, k int) []result {
+	k = min(k, len(index.palette))
+	if index.embed == nil {
+		results := make([]result, len(index.palette))
+		for i, c := range index.palette {
+			results[i] = result{c, index.distance(src, c.Rgb)}
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Distance < results[j].Distance
+		})
+		return results[:k]
+	}
+
+	// best holds k nearest found so far sorted by squared distance.
+	best := make([]kdCandidate, 0, k)
+	index.search(0, index.embed(src), k, &best)
+	results := make([]result, len(best))
+	for i, candidate := range best {
+		c := index.palette[candidate.color]
+		results[i] = result{c, index.distance(src, c.Rgb)}
+	}
+	return results
+}
+
+type kdCandidate struct {
+	color    int
+	distance float64 // squared
+}
+
+func (index *colorIndex) search(node int, target point, k int, best *[]kdCandidate) {
+	if node < 0 {
+		return
+	}
+	n := &index.nodes[node]
+	d := 0.0
+	for i := range target {
+		d += (target[i] - n.point[i]) * (target[i] - n.point[i])
+	}
+	if len(*best) < k || d < (*best)[len(*best)-1].distance {
+		// Insert keeping order, equal distances keep palette order as the linear scan does.
+		i := sort.Search(len(*best), func(i int) bool {
+			b := (*best)[i]
+			return b.distance > d || b.distance == d && b.color > n.color
+		})
+		if len(*best) < k {
+			*best = append(*best, kdCandidate{})
+		}
+		copy((*best)[i+1:], (*best)[i:])
+		(*best)[i] = kdCandidate{n.color, d}
+	}
+
+	diff := target[n.axis] - n.point[n.axis]
+	near, far := n.left, n.right
+	if diff > 0 {
+		near, far = far, near
+	}
+	index.search(near, target, k, best)
+	if len(*best) < k || diff*diff <= (*best)[len(*best)-1].distance {
+		index.search(far, target, k, best)
+	}
+}
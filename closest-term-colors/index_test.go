@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomColors(rnd *rand.Rand, n int) []rgb {
+	colors := make([]rgb, n)
+	for i := range colors {
+		colors[i] = rgb{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256))}
+	}
+	return colors
+}
+
+func randomPalette(rnd *rand.Rand, n int) []color {
+	palette := make([]color, n)
+	for i, c := range randomColors(rnd, n) {
+		palette[i] = newColor(i, c, fmt.Sprintf("color%d", i))
+	}
+	return palette
+}
+
+// TestColorIndex checks that the index finds the same distances as the linear scan.
+func TestColorIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	xterm256, err := loadPalette("xterm256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	palettes := map[string][]color{"xterm256": xterm256, "random1000": randomPalette(rnd, 1000)}
+	queries := randomColors(rnd, 500)
+
+	for paletteName, palette := range palettes {
+		for metricName := range metrics {
+			index, err := newColorIndex(palette, metricName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, src := range queries {
+				want, err := findClosestColors(src, 5, palette, metricName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := index.Nearest(src, 5)
+				if len(got) != len(want) {
+					t.Fatalf("%s, %s, %v: got %d results, want %d", paletteName, metricName, src, len(got), len(want))
+				}
+				for i := range want {
+					if math.Abs(got[i].Distance-want[i].Distance) > 1e-9 {
+						t.Errorf("%s, %s, %v: result %d has distance %g, want %g", paletteName, metricName, src, i, got[i].Distance, want[i].Distance)
+					}
+				}
+			}
+		}
+	}
+}
+
+func benchmarkPalettes(b *testing.B) map[string][]color {
+	xterm256, err := loadPalette("xterm256")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return map[string][]color{"xterm256": xterm256, "random4096": randomPalette(rand.New(rand.NewSource(1)), 4096)}
+}
+
+func BenchmarkLinearScan(b *testing.B) {
+	queries := randomColors(rand.New(rand.NewSource(2)), 1024)
+	for paletteName, palette := range benchmarkPalettes(b) {
+		for _, metricName := range []string{"rgb", "oklab"} {
+			b.Run(paletteName+"/"+metricName, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					findClosestColors(queries[i%len(queries)], 1, palette, metricName)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkColorIndex(b *testing.B) {
+	queries := randomColors(rand.New(rand.NewSource(2)), 1024)
+	for paletteName, palette := range benchmarkPalettes(b) {
+		for _, metricName := range []string{"rgb", "oklab"} {
+			index, err := newColorIndex(palette, metricName)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(paletteName+"/"+metricName, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					index.Nearest(queries[i%len(queries)], 1)
+				}
+			})
+		}
+	}
+}
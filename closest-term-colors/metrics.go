@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/andbar-ru/closest_colors"
+)
+
+// metric returns distance between two colors, the less the closer.
+type metric func(a, b rgb) float64
+
+const defaultMetric = "rgb"
+
+// metrics by name. The "rgb" metric (Euclidean distance in RGB space) is computed by closest_colors.
+var metrics = map[string]metric{
+	"rgb":       rgbDistance,
+	"redmean":   redmeanDistance,
+	"cie76":     cie76Distance,
+	"ciede2000": ciede2000Distance,
+	"oklab":     oklabDistance,
+}
+
+func metricNames() string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+type result struct {
+	Color    color
+	Distance float64
+}
+
+// findClosestColors returns n colors from palette closest to src according to the named metric,
+// in increasing distance order.
+func findClosestColors(src rgb, n int, palette []color, metricName string) ([]result, error) {
+	distance, ok := metrics[metricName]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q, must be one of: %s", metricName, metricNames())
+	}
+	if n < 1 || n > len(palette) {
+		return nil, fmt.Errorf("number of colors must be between 1 and %d, got %d", len(palette), n)
+	}
+
+	if metricName == "rgb" {
+		rgbColors := make([]closest_colors.RGBColor, len(palette))
+		for i, c := range palette {
+			rgbColors[i] = c
+		}
+		closest, err := closest_colors.FindClosestRGBColors(src, n, rgbColors)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]result, len(closest))
+		for i, r := range closest {
+			results[i] = result{r.Color.(color), r.Distance}
+		}
+		return results, nil
+	}
+
+	results := make([]result, len(palette))
+	for i, c := range palette {
+		results[i] = result{c, distance(src, c.Rgb)}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Distance < results[j].Distance
+	})
+	return results[:n], nil
+}
+
+func rgbDistance(a, b rgb) float64 {
+	dr := float64(a.Red) - float64(b.Red)
+	dg := float64(a.Green) - float64(b.Green)
+	db := float64(a.Blue) - float64(b.Blue)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// redmeanDistance is a cheap approximation of perceived difference weighting channels by the mean red
+// level, see https://www.compuphase.com/cmetric.htm.
+func redmeanDistance(a, b rgb) float64 {
+	rmean := (float64(a.Red) + float64(b.Red)) / 2
+	dr := float64(a.Red) - float64(b.Red)
+	dg := float64(a.Green) - float64(b.Green)
+	db := float64(a.Blue) - float64(b.Blue)
+	return math.Sqrt((2+rmean/256)*dr*dr + 4*dg*dg + (2+(255-rmean)/256)*db*db)
+}
+
+func cie76Distance(a, b rgb) float64 {
+	l1, a1, b1 := a.lab()
+	l2, a2, b2 := b.lab()
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+func oklabDistance(a, b rgb) float64 {
+	l1, a1, b1 := a.oklab()
+	l2, a2, b2 := b.oklab()
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// ciede2000Distance implements CIEDE2000 color difference with kL = kC = kH = 1 as described in
+// G. Sharma, W. Wu, E. N. Dalal, "The CIEDE2000 color-difference formula".
+func ciede2000Distance(x, y rgb) float64 {
+	l1, a1, b1 := x.lab()
+	l2, a2, b2 := y.lab()
+	return ciede2000(l1, a1, b1, l2, a2, b2)
+}
+
+func ciede2000(l1, a1, b1, l2, a2, b2 float64) float64 {
+	c1 := math.Hypot(a1, b1)
+	c2 := math.Hypot(a2, b2)
+	cMean7 := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cMean7/(cMean7+math.Pow(25, 7))))
+	a1p, a2p := (1+g)*a1, (1+g)*a2
+	c1p, c2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+	h1p, h2p := hueAngle(b1, a1p), hueAngle(b2, a2p)
+
+	dLp := l2 - l1
+	dCp := c2p - c1p
+	dhp := 0.0
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(radians(dhp/2))
+
+	lMean := (l1 + l2) / 2
+	cMean := (c1p + c2p) / 2
+	hMean := h1p + h2p
+	if c1p*c2p != 0 {
+		switch {
+		case math.Abs(h1p-h2p) <= 180:
+			hMean /= 2
+		case hMean < 360:
+			hMean = (hMean + 360) / 2
+		default:
+			hMean = (hMean - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos(radians(hMean-30)) + 0.24*math.Cos(radians(2*hMean)) +
+		0.32*math.Cos(radians(3*hMean+6)) - 0.20*math.Cos(radians(4*hMean-63))
+	dTheta := 30 * math.Exp(-((hMean-275)/25)*((hMean-275)/25))
+	cMean7 = math.Pow(cMean, 7)
+	rc := 2 * math.Sqrt(cMean7/(cMean7+math.Pow(25, 7)))
+	sl := 1 + 0.015*(lMean-50)*(lMean-50)/math.Sqrt(20+(lMean-50)*(lMean-50))
+	sc := 1 + 0.045*cMean
+	sh := 1 + 0.015*cMean*t
+	rt := -math.Sin(radians(2*dTheta)) * rc
+
+	return math.Sqrt((dLp/sl)*(dLp/sl) + (dCp/sc)*(dCp/sc) + (dHp/sh)*(dHp/sh) + rt*(dCp/sc)*(dHp/sh))
+}
+
+// hueAngle returns hue angle in degrees in range [0, 360).
+func hueAngle(b, a float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// linear returns linear-light sRGB components in range [0, 1].
+func (c rgb) linear() (float64, float64, float64) {
+	f := func(v uint8) float64 {
+		x := float64(v) / 255
+		if x <= 0.04045 {
+			return x / 12.92
+		}
+		return math.Pow((x+0.055)/1.055, 2.4)
+	}
+	return f(c.Red), f(c.Green), f(c.Blue)
+}
+
+// lab converts color to CIE L*a*b* with D65 white point.
+func (c rgb) lab() (float64, float64, float64) {
+	r, g, b := c.linear()
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+	f := func(t float64) float64 {
+		const delta = 6.0 / 29
+		if t > delta*delta*delta {
+			return math.Cbrt(t)
+		}
+		return t/(3*delta*delta) + 4.0/29
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// oklab converts color to OKLab, see https://bottosson.github.io/posts/oklab/.
+func (c rgb) oklab() (float64, float64, float64) {
+	r, g, b := c.linear()
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+	return 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s
+}
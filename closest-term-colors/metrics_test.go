@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCiede2000 checks the test data of G. Sharma, W. Wu, E. N. Dalal, "The CIEDE2000 color-difference
+// formula: implementation notes, supplementary test data, and mathematical observations".
+func TestCiede2000(t *testing.T) {
+	tests := []struct {
+		pair   int
+		lab1   [3]float64
+		lab2   [3]float64
+		deltaE float64
+	}{
+		{1, [3]float64{50, 2.6772, -79.7751}, [3]float64{50, 0, -82.7485}, 2.0425},
+		{2, [3]float64{50, 3.1571, -77.2803}, [3]float64{50, 0, -82.7485}, 2.8615},
+		{7, [3]float64{50, 0, 0}, [3]float64{50, -1, 2}, 2.3669},
+		{17, [3]float64{50, 2.5, 0}, [3]float64{73, 25, -18}, 27.1492},
+		{18, [3]float64{50, 2.5, 0}, [3]float64{61, -5, 29}, 22.8977},
+		{19, [3]float64{50, 2.5, 0}, [3]float64{56, -27, -3}, 31.9030},
+		{20, [3]float64{50, 2.5, 0}, [3]float64{58, 24, 15}, 19.4535},
+		{25, [3]float64{60.2574, -34.0099, 36.2677}, [3]float64{60.4626, -34.1751, 39.4387}, 1.2644},
+		{26, [3]float64{63.0109, -31.0961, -5.8663}, [3]float64{62.8187, -29.7946, -4.0864}, 1.2630},
+		{27, [3]float64{61.2901, 3.7196, -5.3901}, [3]float64{61.4292, 2.2480, -4.9620}, 1.8731},
+		{28, [3]float64{35.0831, -44.1164, 3.7933}, [3]float64{35.0232, -40.0716, 1.5901}, 1.8645},
+		{29, [3]float64{22.7233, 20.0904, -46.6940}, [3]float64{23.0331, 14.9730, -42.5619}, 2.0373},
+		{30, [3]float64{36.4612, 47.8580, 18.3852}, [3]float64{36.2715, 50.5065, 21.2231}, 1.4146},
+		{31, [3]float64{90.8027, -2.0831, 1.4410}, [3]float64{91.1528, -1.6435, 0.0447}, 1.4441},
+		{32, [3]float64{90.9257, -0.5406, -0.9208}, [3]float64{88.6381, -0.8985, -0.7239}, 1.5381},
+		{33, [3]float64{6.7747, -0.2908, -2.4247}, [3]float64{5.8714, -0.0985, -2.2286}, 0.6377},
+		{34, [3]float64{2.0776, 0.0795, -1.1350}, [3]float64{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for _, test := range tests {
+		a, b := test.lab1, test.lab2
+		// The formula is symmetric, so both orders must give the same difference.
+		for _, deltaE := range []float64{ciede2000(a[0], a[1], a[2], b[0], b[1], b[2]), ciede2000(b[0], b[1], b[2], a[0], a[1], a[2])} {
+			if math.Abs(deltaE-test.deltaE) > 1e-4 {
+				t.Errorf("pair %d: got %.4f, want %.4f", test.pair, deltaE, test.deltaE)
+			}
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		c     rgb
+		lab   [3]float64
+		oklab [3]float64
+	}{
+		{rgb{0, 0, 0}, [3]float64{0, 0, 0}, [3]float64{0, 0, 0}},
+		{rgb{255, 255, 255}, [3]float64{100, 0, 0}, [3]float64{1, 0, 0}},
+		{rgb{255, 0, 0}, [3]float64{53.2408, 80.0925, 67.2032}, [3]float64{0.6280, 0.2249, 0.1258}},
+		{rgb{0, 255, 0}, [3]float64{87.7347, -86.1827, 83.1793}, [3]float64{0.8664, -0.2339, 0.1795}},
+		{rgb{0, 0, 255}, [3]float64{32.2970, 79.1875, -107.8602}, [3]float64{0.4520, -0.0325, -0.3115}},
+	}
+	for _, test := range tests {
+		l, a, b := test.c.lab()
+		if math.Abs(l-test.lab[0]) > 1e-2 || math.Abs(a-test.lab[1]) > 1e-2 || math.Abs(b-test.lab[2]) > 1e-2 {
+			t.Errorf("%v: got L*a*b* (%.4f, %.4f, %.4f), want %v", test.c, l, a, b, test.lab)
+		}
+		l, a, b = test.c.oklab()
+		if math.Abs(l-test.oklab[0]) > 1e-4 || math.Abs(a-test.oklab[1]) > 1e-4 || math.Abs(b-test.oklab[2]) > 1e-4 {
+			t.Errorf("%v: got OKLab (%.4f, %.4f, %.4f), want %v", test.c, l, a, b, test.oklab)
+		}
+	}
+}
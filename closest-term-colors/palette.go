@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultPalette = "xterm256"
+
+//go:embed colors.json
+var colorsJson []byte
+
+var (
+	// Xresources: "*.color1: #cc6666", "URxvt*color1 : base08" after "#define base08 #cc6666".
+	xresourcesRegex = regexp.MustCompile(`(?im)^\s*[\w.*-]*color(\d+)\s*:\s*(\S+)`)
+	defineRegex     = regexp.MustCompile(`(?m)^\s*#define\s+(\S+)\s+(#[0-9a-fA-F]{6})\b`)
+	// kitty: "color1 #cc6666".
+	kittyRegex = regexp.MustCompile(`(?im)^\s*color(\d+)\s+(#[0-9a-f]{6})\b`)
+	// Alacritty (TOML or YAML): section "[colors.normal]" or "normal:", then "red = '#cc6666'" or "red: '0xcc6666'".
+	alacrittySectionRegex      = regexp.MustCompile(`^\s*(?:\[colors\.(normal|bright)\]|(normal|bright)\s*:)\s*$`)
+	alacrittyOtherSectionRegex = regexp.MustCompile(`^\s*\w+\s*:\s*$`)
+	alacrittyColorRegex        = regexp.MustCompile(`(?i)^\s*(black|red|green|yellow|blue|magenta|cyan|white)\s*[:=]\s*['"]?(?:#|0x)([0-9a-f]{6})`)
+	// base46 (NvChad) theme: "base08 = "#cc6666"," inside "M.base_16 = { ... }".
+	base46Regex = regexp.MustCompile(`(?i)(base0[0-9a-f])\s*=\s*["'](#[0-9a-f]{6})["']`)
+)
+
+var alacrittyNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// base16Terminal maps terminal colors 0-15 to base16 colors, as base16-shell does.
+var base16Terminal = []string{
+	"base00", "base08", "base0B", "base0A", "base0D", "base0E", "base0C", "base05",
+	"base03", "base08", "base0B", "base0A", "base0D", "base0E", "base0C", "base07",
+}
+
+func paletteNames() string {
+	return "ansi16, xterm88, xterm256, file:<path>"
+}
+
+// loadPalette returns palette by its specification: ansi16, xterm88, xterm256 or file:<path>, where the file
+// is a color table in JSON format or an Xresources, kitty, Alacritty or base46 theme.
+func loadPalette(spec string) ([]color, error) {
+	switch spec {
+	case "xterm256":
+		return parseJsonPalette(colorsJson)
+	case "ansi16":
+		palette, err := parseJsonPalette(colorsJson)
+		if err != nil {
+			return nil, err
+		}
+		return palette[:16], nil
+	case "xterm88":
+		return xterm88Palette()
+	}
+	path, ok := strings.CutPrefix(spec, "file:")
+	if !ok {
+		return nil, fmt.Errorf("unknown palette %q, must be one of: %s", spec, paletteNames())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	palette, err := parsePalette(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return palette, nil
+}
+
+// fetchPalette downloads color table in JSON format.
+func fetchPalette(url string) ([]color, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status code error: %s", url, response.Status)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseJsonPalette(data)
+}
+
+// parsePalette detects format of the theme file by its content and parses it.
+func parsePalette(data []byte) ([]color, error) {
+	var indexed map[int]string
+	switch {
+	case json.Valid(data): // Alacritty TOML also starts with '[', so the prefix is not enough
+		return parseJsonPalette(data)
+	case bytes.Contains(data, []byte("base_16")):
+		indexed = parseBase46(data)
+	case kittyRegex.Match(data):
+		indexed = parseKitty(data)
+	case xresourcesRegex.Match(data):
+		indexed = parseXresources(data)
+	default:
+		indexed = parseAlacritty(data)
+	}
+	if len(indexed) == 0 {
+		return nil, fmt.Errorf("no colors found, expected color table in JSON format or Xresources, kitty, Alacritty or base46 theme")
+	}
+
+	var palette []color
+	for id := 0; id < 256; id++ {
+		hex, ok := indexed[id]
+		if !ok {
+			continue
+		}
+		c, err := parseHex(hex)
+		if err != nil {
+			return nil, fmt.Errorf("color%d: %w", id, err)
+		}
+		palette = append(palette, color{Id: id, Rgb: c, Hex: strings.ToLower(hex), Name: fmt.Sprintf("color%d", id)})
+	}
+	return palette, nil
+}
+
+func parseJsonPalette(data []byte) ([]color, error) {
+	var palette []color
+	if err := json.Unmarshal(data, &palette); err != nil {
+		return nil, fmt.Errorf("invalid color table: %w", err)
+	}
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("color table is empty")
+	}
+	return palette, nil
+}
+
+func parseXresources(data []byte) map[int]string {
+	defines := make(map[string]string)
+	for _, m := range defineRegex.FindAllSubmatch(data, -1) {
+		defines[string(m[1])] = string(m[2])
+	}
+	indexed := make(map[int]string)
+	for _, m := range xresourcesRegex.FindAllSubmatch(data, -1) {
+		id, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			continue
+		}
+		value := string(m[2])
+		if hex, ok := defines[value]; ok {
+			value = hex
+		}
+		indexed[id] = value
+	}
+	return indexed
+}
+
+func parseKitty(data []byte) map[int]string {
+	indexed := make(map[int]string)
+	for _, m := range kittyRegex.FindAllSubmatch(data, -1) {
+		id, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			continue
+		}
+		indexed[id] = string(m[2])
+	}
+	return indexed
+}
+
+func parseAlacritty(data []byte) map[int]string {
+	indexed := make(map[int]string)
+	offset := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := alacrittySectionRegex.FindStringSubmatch(line); m != nil {
+			offset = 0
+			if m[1] == "bright" || m[2] == "bright" {
+				offset = 8
+			}
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "[") || alacrittyOtherSectionRegex.MatchString(line) {
+			offset = -1
+			continue
+		}
+		m := alacrittyColorRegex.FindStringSubmatch(line)
+		if m == nil || offset < 0 {
+			continue
+		}
+		for i, name := range alacrittyNames {
+			if strings.EqualFold(m[1], name) {
+				indexed[offset+i] = "#" + m[2]
+			}
+		}
+	}
+	return indexed
+}
+
+func parseBase46(data []byte) map[int]string {
+	base16 := make(map[string]string)
+	for _, m := range base46Regex.FindAllSubmatch(data, -1) {
+		base16[strings.ToLower(string(m[1]))] = string(m[2])
+	}
+	indexed := make(map[int]string)
+	for id, name := range base16Terminal {
+		if hex, ok := base16[strings.ToLower(name)]; ok {
+			indexed[id] = hex
+		}
+	}
+	return indexed
+}
+
+// xterm88Palette generates the 88-color palette of rxvt-unicode and xterm built with 88 colors:
+// 16 system colors, 4x4x4 color cube and 8 grays.
+func xterm88Palette() ([]color, error) {
+	palette, err := parseJsonPalette(colorsJson)
+	if err != nil {
+		return nil, err
+	}
+	palette = palette[:16:16]
+	cubeLevels := []uint8{0, 139, 205, 255}
+	for i := 0; i < 64; i++ {
+		c := rgb{cubeLevels[i/16], cubeLevels[i/4%4], cubeLevels[i%4]}
+		palette = append(palette, newColor(16+i, c, fmt.Sprintf("Cube%d%d%d", i/16, i/4%4, i%4)))
+	}
+	for i, level := range []uint8{46, 92, 115, 139, 162, 185, 208, 231} {
+		palette = append(palette, newColor(80+i, rgb{level, level, level}, fmt.Sprintf("Grey%d", int(level)*100/255)))
+	}
+	return palette, nil
+}
+
+func newColor(id int, c rgb, name string) color {
+	return color{Id: id, Rgb: c, Hex: fmt.Sprintf("#%02x%02x%02x", c.Red, c.Green, c.Blue), Name: name}
+}
+
+// parseHex parses color in "#rrggbb" format.
+func parseHex(s string) (rgb, error) {
+	submatches := colorRegex.FindStringSubmatch(s)
+	if submatches == nil {
+		return rgb{}, fmt.Errorf("color %q does not match regexp %s", s, colorRegex)
+	}
+	var components [3]uint8
+	for i := range components {
+		value, err := strconv.ParseUint(submatches[i+1], 16, 8)
+		if err != nil {
+			return rgb{}, err
+		}
+		components[i] = uint8(value)
+	}
+	return rgb{components[0], components[1], components[2]}, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestLoadPaletteFile checks that the bundled table loaded as a file is the same as the built-in one.
+func TestLoadPaletteFile(t *testing.T) {
+	xterm256, err := loadPalette("xterm256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	palette, err := loadPalette("file:colors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(palette, xterm256) {
+		t.Errorf("file:colors.json differs from xterm256")
+	}
+}
+
+func TestLoadPaletteAlacritty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alacritty.toml")
+	theme := "[colors.normal]\nblack = '#1d1f21'\nred = '#cc6666'\n\n[colors.bright]\nred = '0xd54e53'\n"
+	if err := os.WriteFile(path, []byte(theme), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	palette, err := loadPalette("file:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range palette {
+		got = append(got, c.Hex)
+	}
+	if want := []string{"#1d1f21", "#cc6666", "#d54e53"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
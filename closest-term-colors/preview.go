@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	swatch     = "        "
+	sampleText = " The quick brown fox "
+	reset      = "\x1b[0m"
+)
+
+// printPreview prints a truecolor swatch of the source color and text samples in foreground and background
+// usage, then the same for each result, so the results can be compared with the source by eye. With indexed
+// the results are shown by their palette index (38;5;N and 48;5;N) as the terminal renders them, otherwise
+// ids are not xterm indices and the results are shown in truecolor.
+func printPreview(w io.Writer, src rgb, results []result, indexed bool) {
+	srcFg := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", src.Red, src.Green, src.Blue)
+	srcBg := fmt.Sprintf("\x1b[48;2;%d;%d;%dm", src.Red, src.Green, src.Blue)
+	fmt.Fprintf(w, "%-8s %s%s%s %s%s%s %s%s%s%s #%02x%02x%02x\n", "source",
+		srcBg, swatch, reset,
+		srcFg, sampleText, reset,
+		srcBg, contrastFg(src), sampleText, reset,
+		src.Red, src.Green, src.Blue)
+	for _, result := range results {
+		fg, bg := fgEscape(result.Color, indexed), bgEscape(result.Color, indexed)
+		fmt.Fprintf(w, "%-8d %s%s%s %s%s%s %s%s%s%s %s: %s (distance %.4g)\n", result.Color.Id,
+			bg, swatch, reset,
+			fg, sampleText, reset,
+			bg, contrastFg(result.Color.Rgb), sampleText, reset,
+			result.Color.Hex, result.Color.Name, result.Distance)
+	}
+}
+
+// fgEscape returns escape sequence setting foreground to the palette color, by index or in truecolor.
+func fgEscape(c color, indexed bool) string {
+	if indexed {
+		return fmt.Sprintf("\x1b[38;5;%dm", c.Id)
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.Rgb.Red, c.Rgb.Green, c.Rgb.Blue)
+}
+
+// bgEscape returns escape sequence setting background to the palette color, by index or in truecolor.
+func bgEscape(c color, indexed bool) string {
+	if indexed {
+		return fmt.Sprintf("\x1b[48;5;%dm", c.Id)
+	}
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.Rgb.Red, c.Rgb.Green, c.Rgb.Blue)
+}
+
+// contrastFg returns escape sequence for black or white foreground, whichever is more readable on the background.
+func contrastFg(bg rgb) string {
+	l, _, _ := bg.lab()
+	if l > 55 {
+		return "\x1b[38;2;0;0;0m"
+	}
+	return "\x1b[38;2;255;255;255m"
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Maximum number of results per request.
+const maxResults = 256
+
+type closestResponse struct {
+	Color   string          `json:"color"`
+	Metric  string          `json:"metric"`
+	Palette string          `json:"palette"`
+	Results []closestResult `json:"results"`
+}
+
+type closestResult struct {
+	Id       int     `json:"id"`
+	Hex      string  `json:"hex"`
+	Name     string  `json:"name"`
+	Rgb      rgb     `json:"rgb"`
+	Distance float64 `json:"distance"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// server answers nearest color queries over HTTP.
+type server struct {
+	palette     []color // default palette, the one given via command-line options
+	paletteName string
+
+	mutex    sync.Mutex
+	builtins map[string][]color // loaded built-in palettes
+}
+
+func newServer(palette []color, paletteName string) *server {
+	return &server{palette: palette, paletteName: paletteName, builtins: make(map[string][]color)}
+}
+
+// Handler returns HTTP handler of the service.
+func (s *server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /closest", s.handleClosest)
+	return mux
+}
+
+// handleClosest serves /closest?color=...&n=...&metric=...&palette=...
+// Only n closest colors are required, n defaults to 1, metric to rgb and palette to the default one.
+// Only built-in palettes can be requested, palette files are not read on behalf of clients.
+func (s *server) handleClosest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	paletteName := query.Get("palette")
+	palette, err := s.getPalette(paletteName)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	if paletteName == "" {
+		paletteName = s.paletteName
+	}
+
+	if query.Get("color") == "" {
+		writeJson(w, http.StatusBadRequest, errorResponse{"color is required"})
+		return
+	}
+	src, err := parseColor(query.Get("color"), palette)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	n := 1
+	if query.Has("n") {
+		n, err = strconv.Atoi(query.Get("n"))
+		if err != nil || n < 1 || n > maxResults {
+			writeJson(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("n must be integer between 1 and %d", maxResults)})
+			return
+		}
+	}
+	n = min(n, len(palette))
+
+	metricName := query.Get("metric")
+	if metricName == "" {
+		metricName = defaultMetric
+	}
+
+	results, err := findClosestColors(src, n, palette, metricName)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	response := closestResponse{
+		Color:   fmt.Sprintf("#%02x%02x%02x", src.Red, src.Green, src.Blue),
+		Metric:  metricName,
+		Palette: paletteName,
+		Results: make([]closestResult, len(results)),
+	}
+	for i, result := range results {
+		response.Results[i] = closestResult{result.Color.Id, result.Color.Hex, result.Color.Name, result.Color.Rgb, result.Distance}
+	}
+	writeJson(w, http.StatusOK, response)
+}
+
+func (s *server) getPalette(name string) ([]color, error) {
+	switch name {
+	case "":
+		return s.palette, nil
+	case "ansi16", "xterm88", "xterm256":
+	default:
+		return nil, fmt.Errorf("unknown palette %q, must be one of: ansi16, xterm88, xterm256", name)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if palette, ok := s.builtins[name]; ok {
+		return palette, nil
+	}
+	palette, err := loadPalette(name)
+	if err != nil {
+		return nil, err
+	}
+	s.builtins[name] = palette
+	return palette, nil
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+func printServeHelpAndExit(fs *flag.FlagSet) {
+	fmt.Printf("Usage: %s [options] serve [serve options]\n", filepath.Base(os.Args[0]))
+	fmt.Println("Serves nearest color queries over HTTP: GET /closest?color=...&n=...&metric=...&palette=...")
+	fmt.Println("returns JSON. palette may be ansi16, xterm88 or xterm256, by default the palette given via options.")
+	fmt.Println("Serve options:")
+	fs.PrintDefaults()
+	os.Exit(1)
+}
+
+// serveMain runs the serve subcommand.
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "listen on `address`")
+	fs.Usage = func() { printServeHelpAndExit(fs) }
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		printServeHelpAndExit(fs)
+	}
+
+	paletteName := *paletteSpec
+	switch {
+	case *paletteUrl != "":
+		paletteName = *paletteUrl
+	case *paletteFile != "":
+		paletteName = "file:" + *paletteFile
+	}
+	s := newServer(colors, paletteName)
+	log.Printf("Listening on http://%s/closest", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s.Handler()))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	palette, err := loadPalette(defaultPalette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newServer(palette, defaultPalette).Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getClosest(t *testing.T, ts *httptest.Server, query url.Values, v any) int {
+	t.Helper()
+	response, err := http.Get(ts.URL + "/closest?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode
+}
+
+func TestServeClosest(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		query   url.Values
+		metric  string
+		palette string
+		ids     []int
+	}{
+		{url.Values{"color": {"#5f5fd7"}}, "rgb", "xterm256", []int{62}},
+		{url.Values{"color": {"#5F5FD7"}, "n": {"3"}, "metric": {"oklab"}}, "oklab", "xterm256", []int{62, 63, 61}},
+		{url.Values{"color": {"rgb(0, 0, 95)"}, "metric": {"ciede2000"}}, "ciede2000", "xterm256", []int{17}},
+		{url.Values{"color": {"9"}, "metric": {"cie76"}, "palette": {"ansi16"}}, "cie76", "ansi16", []int{9}},
+		{url.Values{"color": {"navy"}, "metric": {"redmean"}, "palette": {"xterm88"}, "n": {"2"}}, "redmean", "xterm88", []int{4, 17}},
+		// n is limited by the palette size.
+		{url.Values{"color": {"#000"}, "n": {"100"}, "metric": {"cie76"}, "palette": {"ansi16"}}, "cie76", "ansi16", nil},
+	}
+	for _, test := range tests {
+		var response closestResponse
+		if status := getClosest(t, ts, test.query, &response); status != http.StatusOK {
+			t.Errorf("%v: status %d, want %d", test.query, status, http.StatusOK)
+			continue
+		}
+		if response.Metric != test.metric || response.Palette != test.palette {
+			t.Errorf("%v: metric %q, palette %q, want %q, %q", test.query, response.Metric, response.Palette, test.metric, test.palette)
+		}
+		if test.ids == nil {
+			if len(response.Results) != 16 {
+				t.Errorf("%v: got %d results, want 16", test.query, len(response.Results))
+			}
+			continue
+		}
+		if len(response.Results) != len(test.ids) {
+			t.Errorf("%v: got %d results, want %d", test.query, len(response.Results), len(test.ids))
+			continue
+		}
+		for i, result := range response.Results {
+			if result.Id != test.ids[i] {
+				t.Errorf("%v: result %d has id %d, want %d", test.query, i, result.Id, test.ids[i])
+			}
+			if i > 0 && result.Distance < response.Results[i-1].Distance {
+				t.Errorf("%v: results are not sorted by distance", test.query)
+			}
+		}
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	ts := newTestServer(t)
+
+	queries := []url.Values{
+		{},
+		{"color": {"bogus"}},
+		{"color": {"#123456"}, "n": {"0"}},
+		{"color": {"#123456"}, "n": {"x"}},
+		{"color": {"#123456"}, "metric": {"bogus"}},
+		{"color": {"#123456"}, "palette": {"bogus"}},
+		{"color": {"#123456"}, "palette": {"file:/etc/passwd"}},
+		{"color": {"100"}, "palette": {"ansi16"}},
+	}
+	for _, query := range queries {
+		var response errorResponse
+		if status := getClosest(t, ts, query, &response); status != http.StatusBadRequest {
+			t.Errorf("%v: status %d, want %d", query, status, http.StatusBadRequest)
+		}
+		if response.Error == "" {
+			t.Errorf("%v: empty error message", query)
+		}
+	}
+}
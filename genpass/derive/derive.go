@@ -0,0 +1,277 @@
+/*
+Пакет derive содержит алгоритм genpass: сборку набора символов и вычисление паролей по фразе
+или случайным образом. Пароль по фразе однозначно определяется фразой и параметрами, поэтому
+пакет можно использовать вместо запуска genpass, например для изучения распределения символов.
+*/
+package derive
+
+import (
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	// Ambiguous - неоднозначные символы, которые легко спутать друг с другом.
+	Ambiguous = "Il1O0"
+	// MaxAttempts - максимальное количество попыток подобрать пароль, удовлетворяющий требованию Min.
+	MaxAttempts = 1 << 20
+	// MaxLength - максимальная длина пароля: сумма SHA-512 плюс максимум, который выдаёт HKDF-Expand (255 блоков).
+	MaxLength = sha512.Size + 255*sha512.Size
+
+	// Контекст HKDF-Expand, чтобы поток байтов genpass не совпадал с другими применениями HKDF.
+	hkdfInfo = "genpass"
+)
+
+var (
+	// Символы в группах в порядке увеличения кодов ascii.
+	Lower   = []byte("abcdefghijklmnopqrstuvwxyz")
+	Upper   = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	Digits  = []byte("0123456789")
+	Symbols = []byte(" !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~")
+
+	// ErrAttempts возвращается, если за MaxAttempts попыток не удалось подобрать пароль,
+	// удовлетворяющий требованию Min.
+	ErrAttempts = fmt.Errorf("не удалось подобрать пароль за %d попыток", MaxAttempts)
+	// ErrExhausted возвращается, если для пароля не хватило байтов потока (больше MaxLength).
+	ErrExhausted = errors.New("поток байтов исчерпан")
+
+	charactersCapacity = len(Lower) + len(Upper) + len(Digits) + len(Symbols)
+)
+
+// Options - параметры пароля, соответствующие ключам genpass.
+type Options struct {
+	Length  int  // длина пароля (-c)
+	Lower   bool // строчные буквы (-l)
+	Upper   bool // прописные буквы (-u)
+	Digits  bool // цифры (-d)
+	Symbols bool // спецсимволы (-s)
+	// Min - минимальное количество символов каждого класса из Require (-m).
+	Min int
+	// Require - классы, к которым применяется Min: буквы l, u, d, s; пустая строка - все выбранные классы (-r).
+	Require string
+	Include string // дополнительные символы (-i)
+	Exclude string // исключаемые символы (-x)
+	// Ambiguous - исключить символы из константы Ambiguous (-a).
+	Ambiguous bool
+}
+
+// DefaultOptions возвращает параметры genpass по умолчанию: все классы, 12 символов.
+func DefaultOptions() Options {
+	return Options{Length: 12, Lower: true, Upper: true, Digits: true, Symbols: true}
+}
+
+// Class - группа символов, из которой в пароле должно быть не меньше Min символов.
+type Class struct {
+	Name  string
+	Chars []byte
+	Min   int
+}
+
+// without возвращает копию chars без символов из excluded.
+func without(chars []byte, excluded []byte) []byte {
+	result := make([]byte, 0, len(chars))
+	for _, char := range chars {
+		if bytes.IndexByte(excluded, char) == -1 {
+			result = append(result, char)
+		}
+	}
+	return result
+}
+
+// Charset возвращает выбранные классы и набор символов для генерации пароля.
+// Если не выбран ни один класс, то используются все.
+func Charset(opts Options) ([]Class, []byte, error) {
+	if opts.Length > MaxLength || opts.Length < 1 {
+		return nil, nil, fmt.Errorf("длина пароля %d неправильная, должна быть от 1 до %d", opts.Length, MaxLength)
+	}
+	if opts.Min < 0 {
+		return nil, nil, fmt.Errorf("минимальное количество символов класса %d неправильное, должно быть не меньше 0", opts.Min)
+	}
+	if strings.Trim(opts.Require, "luds") != "" {
+		return nil, nil, fmt.Errorf("классы %q неправильные, допустимы только буквы l, u, d, s", opts.Require)
+	}
+	if !opts.Lower && !opts.Upper && !opts.Digits && !opts.Symbols {
+		opts.Lower, opts.Upper, opts.Digits, opts.Symbols = true, true, true, true
+	}
+
+	excluded := []byte(opts.Exclude)
+	if opts.Ambiguous {
+		excluded = append(excluded, Ambiguous...)
+	}
+
+	// Требование Min распространяется на классы из Require, а если Require не задан, то на все выбранные классы.
+	required := func(letter byte) int {
+		if opts.Require == "" || strings.IndexByte(opts.Require, letter) != -1 {
+			return opts.Min
+		}
+		return 0
+	}
+	var classes []Class
+	if opts.Lower {
+		classes = append(classes, Class{"строчные буквы", without(Lower, excluded), required('l')})
+	}
+	if opts.Upper {
+		classes = append(classes, Class{"прописные буквы", without(Upper, excluded), required('u')})
+	}
+	if opts.Digits {
+		classes = append(classes, Class{"цифры", without(Digits, excluded), required('d')})
+	}
+	if opts.Symbols {
+		classes = append(classes, Class{"спецсимволы", without(Symbols, excluded), required('s')})
+	}
+
+	characters := make([]byte, 0, charactersCapacity+len(opts.Include))
+	requiredTotal := 0
+	for _, cl := range classes {
+		if len(cl.Chars) == 0 {
+			return nil, nil, fmt.Errorf("после исключения символов класс «%s» оказался пустым", cl.Name)
+		}
+		characters = append(characters, cl.Chars...)
+		requiredTotal += cl.Min
+	}
+	for _, char := range without([]byte(opts.Include), excluded) {
+		if bytes.IndexByte(characters, char) == -1 {
+			characters = append(characters, char)
+		}
+	}
+
+	if requiredTotal > opts.Length {
+		return nil, nil, fmt.Errorf("нельзя вместить %d обязательных символов в пароль длиной %d", requiredTotal, opts.Length)
+	}
+	return classes, characters, nil
+}
+
+// satisfies проверяет, что в пароле не меньше Min символов каждого класса.
+func satisfies(password []byte, classes []Class) bool {
+	for _, cl := range classes {
+		if cl.Min == 0 {
+			continue
+		}
+		count := 0
+		for _, char := range password {
+			if bytes.IndexByte(cl.Chars, char) != -1 {
+				count++
+			}
+		}
+		if count < cl.Min {
+			return false
+		}
+	}
+	return true
+}
+
+// Password вычисляет пароль по фразе. i-й символ пароля - это символ набора с индексом b % n,
+// где b - i-й байт потока фразы (см. NewStream), n - размер набора.
+// Если пароль не удовлетворяет требованию Min, то сумма SHA-512 хэшируется повторно, пока требование
+// не будет выполнено. Поэтому пароль по-прежнему однозначно определяется фразой.
+func Password(phrase string, opts Options) (string, error) {
+	classes, characters, err := Charset(opts)
+	if err != nil {
+		return "", err
+	}
+	password := make([]byte, opts.Length)
+	sum := sha512.Sum512([]byte(phrase))
+	for attempt := 0; attempt < MaxAttempts; attempt++ {
+		stream := newSumStream(sum).Next(opts.Length)
+		for i := range password {
+			password[i] = characters[int(stream[i])%len(characters)]
+		}
+		if satisfies(password, classes) {
+			return string(password), nil
+		}
+		sum = sha512.Sum512(sum[:])
+	}
+	return "", ErrAttempts
+}
+
+// RandomPassword генерирует случайный пароль из байтов, прочитанных из rnd. Если rnd равен nil,
+// используется crypto/rand.Reader; другой источник нужен только для воспроизводимых тестов.
+func RandomPassword(rnd io.Reader, opts Options) (string, error) {
+	classes, characters, err := Charset(opts)
+	if err != nil {
+		return "", err
+	}
+	password := make([]byte, opts.Length)
+	for attempt := 0; attempt < MaxAttempts; attempt++ {
+		for i := range password {
+			k, err := randomIndex(rnd, len(characters))
+			if err != nil {
+				return "", err
+			}
+			password[i] = characters[k]
+		}
+		if satisfies(password, classes) {
+			return string(password), nil
+		}
+	}
+	return "", ErrAttempts
+}
+
+// randomIndex возвращает равновероятное число от 0 до n-1 из байтов, прочитанных из rnd
+// (crypto/rand.Reader, если rnd равен nil).
+func randomIndex(rnd io.Reader, n int) (int, error) {
+	if rnd == nil {
+		rnd = crand.Reader
+	}
+	k, err := crand.Int(rnd, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(k.Int64()), nil
+}
+
+// Stream - поток байтов, однозначно определяемый суммой SHA-512 фразы.
+// Первые 64 байта - это сама сумма, поэтому пароли длиной до 64 символов совпадают с прежними.
+// Остальные байты получаются с помощью HKDF-Expand (RFC 5869), где в качестве PRK выступает сумма.
+type Stream struct {
+	buf     []byte
+	mac     hash.Hash
+	info    string
+	block   []byte
+	counter byte
+	total   int // сколько байтов уже выдано
+}
+
+// NewStream возвращает поток байтов фразы.
+func NewStream(phrase string) *Stream {
+	return newSumStream(sha512.Sum512([]byte(phrase)))
+}
+
+func newSumStream(sum [sha512.Size]byte) *Stream {
+	s := newHKDFStream(sum[:], hkdfInfo)
+	s.buf = append(s.buf, sum[:]...)
+	return s
+}
+
+// newHKDFStream возвращает поток байтов HKDF-Expand с ключом prk и контекстом info.
+func newHKDFStream(prk []byte, info string) *Stream {
+	return &Stream{mac: hmac.New(sha512.New, prk), info: info}
+}
+
+// Next возвращает следующие n байтов потока или nil, если поток исчерпан (больше MaxLength байтов).
+func (s *Stream) Next(n int) []byte {
+	if s.total+n > MaxLength {
+		return nil
+	}
+	for len(s.buf) < n {
+		s.counter++
+		s.mac.Reset()
+		s.mac.Write(s.block)
+		s.mac.Write([]byte(s.info))
+		s.mac.Write([]byte{s.counter})
+		s.block = s.mac.Sum(nil)
+		s.buf = append(s.buf, s.block...)
+	}
+	result := s.buf[:n]
+	s.buf = s.buf[n:]
+	s.total += n
+	return result
+}
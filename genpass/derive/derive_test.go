@@ -0,0 +1,93 @@
+package derive
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// Пароли, которые выдавала genpass. Если какой-то из них изменится, то все пользователи потеряют
+// доступ к сайтам, поэтому эти векторы менять нельзя.
+var passwordVectors = []struct {
+	phrase string
+	opts   Options
+	want   string
+}{
+	{"foo", DefaultOptions(), `59{pg26XNq|1`},
+	{"bar", Options{Length: 64}, `AS:\MC` + "`" + `k-5[VyEBteJ2vPMUZ<1tu8hQ,F18h>Pc>u*"HH>qAO.4LoY A{L-VdOdI`},
+	{"mastergithub", Options{Length: 20, Lower: true, Upper: true, Digits: true, Min: 2, Require: "d", Exclude: "Il1O0"}, `KqCVtnsZZrx8ML5fpgnx`},
+	{"long", Options{Length: 100}, `INUVU2zw.8ooW7Lo,049@>tmkeM565|mN"xFgl|QYHI6s&&T+k0FW4p4uhr[L;S3S3"VCgA]S^.qFIhp8sU|-;vSW.{Z!tIaM#p1`},
+	{"digits", Options{Length: 16, Digits: true}, `5951155858551170`},
+	{"sym", Options{Length: 30, Symbols: true, Exclude: " ", Ambiguous: true}, `?!(&]?&\|(|\%?]-"=>~$::/$*(` + "`" + `{|`},
+	{"extra", Options{Length: 20, Digits: true, Include: "xyz"}, `828y943z70166402z9y7`},
+	{"policy", Options{Length: 12, Min: 3}, `Ku2#g U8#Xa5`},
+	{"tight", Options{Length: 4, Min: 1}, `a2I"`},
+}
+
+var passphraseVectors = []struct {
+	phrase string
+	opts   WordOptions
+	want   string
+}{
+	{"foo", WordOptions{Words: 6, Sep: "-", Caps: CapsNone}, "clamor-decathlon-outmost-crystal-feline-roundness"},
+	{"bar", WordOptions{Words: 4, Sep: ".", Caps: CapsRandom}, "catfight.verse.catching.ALIENATE"},
+	{"baz", WordOptions{Words: 5, Sep: " ", Caps: CapsFirst}, "Cloak Saucy Thing Finished Glimmer"},
+	{"qux", WordOptions{Words: 3, Sep: "", Caps: CapsUpper}, "PRIMATERIFLINGDRINKABLE"},
+}
+
+func TestPassword(t *testing.T) {
+	for _, v := range passwordVectors {
+		res, err := Password(v.phrase, v.opts)
+		if err != nil {
+			t.Errorf("Password(%q, %+v) error: %v", v.phrase, v.opts, err)
+		} else if res != v.want {
+			t.Errorf("Password(%q, %+v) = %q, want %q", v.phrase, v.opts, res, v.want)
+		}
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	for _, v := range passphraseVectors {
+		res, err := Passphrase(v.phrase, v.opts)
+		if err != nil {
+			t.Errorf("Passphrase(%q, %+v) error: %v", v.phrase, v.opts, err)
+		} else if res != v.want {
+			t.Errorf("Passphrase(%q, %+v) = %q, want %q", v.phrase, v.opts, res, v.want)
+		}
+	}
+}
+
+// TestRandomPasswordPolicy проверяет пароли из crypto/rand (nil) и из воспроизводимого источника.
+func TestRandomPasswordPolicy(t *testing.T) {
+	opts := Options{Length: 8, Min: 2}
+	classes, _, err := Charset(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rnd := range []io.Reader{nil, rand.New(rand.NewSource(1))} {
+		for i := 0; i < 100; i++ {
+			res, err := RandomPassword(rnd, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !satisfies([]byte(res), classes) {
+				t.Errorf("RandomPassword(%+v) = %q does not satisfy the policy", opts, res)
+			}
+		}
+	}
+}
+
+func TestCharsetErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{Length: 0},
+		{Length: MaxLength + 1},
+		{Length: 12, Min: -1},
+		{Length: 12, Require: "x"},
+		{Length: 12, Digits: true, Exclude: "0123456789"},
+		{Length: 7, Min: 2},
+	} {
+		if _, _, err := Charset(opts); err == nil {
+			t.Errorf("Charset(%+v) returned no error", opts)
+		}
+	}
+}
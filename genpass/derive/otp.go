@@ -0,0 +1,133 @@
+package derive
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Контекст HKDF-Expand для секретов одноразовых паролей, чтобы секрет не совпадал с байтами пароля
+	// по той же фразе.
+	otpInfo = "genpass otp"
+	// Максимальный размер секрета: столько выдаёт HKDF-Expand (255 блоков).
+	maxSecretSize = 255 * sha512.Size
+)
+
+// OTP - параметры одноразовых паролей HOTP (RFC 4226) и TOTP (RFC 6238).
+type OTP struct {
+	Secret    []byte
+	Algorithm string // SHA1 (по умолчанию), SHA256 или SHA512
+	Digits    int    // количество цифр кода, по умолчанию 6
+	Period    int    // длительность шага TOTP в секундах, по умолчанию 30
+}
+
+// OTPSecret вычисляет по фразе секрет длиной size байтов для одноразовых паролей.
+// Секрет получается с помощью HKDF-Expand из суммы SHA-512 фразы, как и байты длинных паролей,
+// но с другим контекстом, поэтому он не связан с паролем по той же фразе.
+func OTPSecret(phrase string, size int) ([]byte, error) {
+	if size < 1 || size > maxSecretSize {
+		return nil, fmt.Errorf("размер секрета %d неправильный, должен быть от 1 до %d", size, maxSecretSize)
+	}
+	sum := sha512.Sum512([]byte(phrase))
+	return newHKDFStream(sum[:], otpInfo).Next(size), nil
+}
+
+// Base32 возвращает секрет в кодировке Base32 без выравнивания, как его принимают приложения.
+func (o OTP) Base32() string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(o.Secret)
+}
+
+func (o OTP) hash() (func() hash.Hash, error) {
+	switch strings.ToUpper(o.Algorithm) {
+	case "", "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("неизвестный алгоритм %q, должен быть SHA1, SHA256 или SHA512", o.Algorithm)
+}
+
+func (o OTP) digits() int {
+	if o.Digits == 0 {
+		return 6
+	}
+	return o.Digits
+}
+
+func (o OTP) period() int {
+	if o.Period == 0 {
+		return 30
+	}
+	return o.Period
+}
+
+// HOTP вычисляет код по счётчику counter (RFC 4226).
+func (o OTP) HOTP(counter uint64) (string, error) {
+	h, err := o.hash()
+	if err != nil {
+		return "", err
+	}
+	digits := o.digits()
+	if digits < 1 || digits > 10 {
+		return "", fmt.Errorf("количество цифр %d неправильное, должно быть от 1 до 10", digits)
+	}
+	mac := hmac.New(h, o.Secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	// Динамическое усечение: 31 бит начиная со смещения, заданного младшими 4 битами последнего байта.
+	offset := sum[len(sum)-1] & 0xf
+	code := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+	modulo := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%modulo), nil
+}
+
+// TOTP вычисляет код для момента t (RFC 6238).
+func (o OTP) TOTP(t time.Time) (string, error) {
+	if o.period() < 1 {
+		return "", fmt.Errorf("длительность шага %d неправильная, должна быть больше 0", o.period())
+	}
+	return o.HOTP(uint64(t.Unix()) / uint64(o.period()))
+}
+
+// URI возвращает ссылку otpauth:// для добавления секрета в приложение. Если hotp, то ссылка
+// для HOTP с начальным счётчиком counter, иначе для TOTP.
+func (o OTP) URI(issuer, account string, hotp bool, counter uint64) string {
+	label := account
+	if issuer != "" {
+		label = issuer + ":" + account
+	}
+	params := url.Values{}
+	params.Set("secret", o.Base32())
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", strings.ToUpper(o.Algorithm))
+	if o.Algorithm == "" {
+		params.Set("algorithm", "SHA1")
+	}
+	params.Set("digits", strconv.Itoa(o.digits()))
+	kind := "totp"
+	if hotp {
+		kind = "hotp"
+		params.Set("counter", strconv.FormatUint(counter, 10))
+	} else {
+		params.Set("period", strconv.Itoa(o.period()))
+	}
+	u := url.URL{Scheme: "otpauth", Host: kind, Path: "/" + label, RawQuery: params.Encode()}
+	return u.String()
+}
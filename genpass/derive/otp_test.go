@@ -0,0 +1,74 @@
+package derive
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые векторы из приложения D RFC 4226.
+var hotpVectors = []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+// Тестовые векторы из приложения B RFC 6238.
+var totpVectors = []struct {
+	time      int64
+	algorithm string
+	want      string
+}{
+	{59, "SHA1", "94287082"},
+	{59, "SHA256", "46119246"},
+	{59, "SHA512", "90693936"},
+	{1111111109, "SHA1", "07081804"},
+	{1111111109, "SHA256", "68084774"},
+	{1111111109, "SHA512", "25091201"},
+	{1234567890, "SHA1", "89005924"},
+	{2000000000, "SHA256", "90698825"},
+	{20000000000, "SHA512", "47863826"},
+}
+
+var totpSecrets = map[string]string{
+	"SHA1":   "12345678901234567890",
+	"SHA256": "12345678901234567890123456789012",
+	"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+}
+
+func TestHOTP(t *testing.T) {
+	otp := OTP{Secret: []byte("12345678901234567890")}
+	for counter, want := range hotpVectors {
+		res, err := otp.HOTP(uint64(counter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != want {
+			t.Errorf("HOTP(%d) = %q, want %q", counter, res, want)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	for _, v := range totpVectors {
+		otp := OTP{Secret: []byte(totpSecrets[v.algorithm]), Algorithm: v.algorithm, Digits: 8}
+		res, err := otp.TOTP(time.Unix(v.time, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != v.want {
+			t.Errorf("TOTP(%d, %s) = %q, want %q", v.time, v.algorithm, res, v.want)
+		}
+	}
+}
+
+func TestOTPSecret(t *testing.T) {
+	secret, err := OTPSecret("foo", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := OTP{Secret: secret}
+	if res, want := otp.Base32(), "UABGLDDKZCYFFSYM2KUKS4CQUPBYPQMX"; res != want {
+		t.Errorf("OTPSecret(%q) = %q, want %q", "foo", res, want)
+	}
+	uri := otp.URI("Example", "alice@example.com", false, 0)
+	if !strings.HasPrefix(uri, "otpauth://totp/Example:alice@example.com?") || !strings.Contains(uri, "secret="+otp.Base32()) {
+		t.Errorf("URI = %q", uri)
+	}
+}
@@ -0,0 +1,126 @@
+package derive
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Список слов EFF для парольных фраз: https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt
+//
+//go:embed eff_large_wordlist.txt
+var effWordlist string
+
+// Способы написания слов в парольной фразе.
+const (
+	CapsNone   = "none"   // все буквы строчные
+	CapsFirst  = "first"  // первая буква каждого слова прописная
+	CapsUpper  = "upper"  // все буквы прописные
+	CapsRandom = "random" // каждое слово случайно пишется строчными или прописными буквами
+)
+
+// WordOptions - параметры парольной фразы из слов, соответствующие ключам genpass.
+type WordOptions struct {
+	Words int    // количество слов (-words)
+	Sep   string // разделитель слов (-sep)
+	Caps  string // регистр слов, одна из констант Caps* (-caps)
+}
+
+// Wordlist возвращает слова из встроенного списка в порядке номеров бросков костей.
+var Wordlist = sync.OnceValue(func() []string {
+	lines := strings.Split(strings.TrimSpace(effWordlist), "\n")
+	words := make([]string, len(lines))
+	for i, line := range lines {
+		_, word, _ := strings.Cut(line, "\t")
+		words[i] = word
+	}
+	return words
+})
+
+func (opts WordOptions) check() error {
+	if opts.Words < 1 {
+		return fmt.Errorf("количество слов %d неправильное, должно быть больше 0", opts.Words)
+	}
+	switch opts.Caps {
+	case CapsNone, CapsFirst, CapsUpper, CapsRandom:
+		return nil
+	}
+	return fmt.Errorf("неизвестный режим регистра %q", opts.Caps)
+}
+
+// capitalize изменяет регистр слова согласно caps. upper используется в режиме CapsRandom.
+func capitalize(word string, caps string, upper bool) string {
+	switch caps {
+	case CapsFirst:
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		return string(runes)
+	case CapsUpper:
+		return strings.ToUpper(word)
+	case CapsRandom:
+		if upper {
+			return strings.ToUpper(word)
+		}
+	}
+	return word
+}
+
+// RandomPassphrase составляет парольную фразу из случайных слов, выбранных по байтам из rnd
+// (crypto/rand.Reader, если rnd равен nil, см. RandomPassword).
+func RandomPassphrase(rnd io.Reader, opts WordOptions) (string, error) {
+	if err := opts.check(); err != nil {
+		return "", err
+	}
+	words := Wordlist()
+	phrase := make([]string, opts.Words)
+	for i := range phrase {
+		k, err := randomIndex(rnd, len(words))
+		if err != nil {
+			return "", err
+		}
+		upper, err := randomIndex(rnd, 2)
+		if err != nil {
+			return "", err
+		}
+		phrase[i] = capitalize(words[k], opts.Caps, upper == 1)
+	}
+	return strings.Join(phrase, opts.Sep), nil
+}
+
+// Passphrase составляет парольную фразу из слов, однозначно определяемых фразой phrase.
+// Номер слова берётся из пары байтов потока фразы (см. NewStream). Чтобы все слова были
+// равновероятны, пары байтов, попадающие в неполный последний интервал, отбрасываются.
+func Passphrase(phrase string, opts WordOptions) (string, error) {
+	if err := opts.check(); err != nil {
+		return "", err
+	}
+	words := Wordlist()
+	s := NewStream(phrase)
+	limit := 1 << 16 / len(words) * len(words)
+	passphrase := make([]string, opts.Words)
+	for i := range passphrase {
+		for {
+			b := s.Next(2)
+			if b == nil {
+				return "", ErrExhausted
+			}
+			if v := int(b[0])<<8 | int(b[1]); v < limit {
+				passphrase[i] = words[v%len(words)]
+				break
+			}
+		}
+		upper := false
+		if opts.Caps == CapsRandom {
+			b := s.Next(1)
+			if b == nil {
+				return "", ErrExhausted
+			}
+			upper = b[0]&1 == 1
+		}
+		passphrase[i] = capitalize(passphrase[i], opts.Caps, upper)
+	}
+	return strings.Join(passphrase, opts.Sep), nil
+}
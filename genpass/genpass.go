@@ -2,36 +2,44 @@
 Скрипт генерирует пароль по фразе.
 Настоятельно рекомендуется перед фразой вводить мастер-пароль, например:
 genpass '<мастер-пароль><фраза>'
+С ключом -words вместо пароля из символов составляется парольная фраза из слов списка EFF.
+Подкоманда otp вычисляет по фразе секрет и коды одноразовых паролей TOTP/HOTP.
 */
 package main
 
 import (
-	"crypto/sha512"
+	"bytes"
 	"flag"
 	"fmt"
-	"math/rand"
 	"os"
-	"time"
-)
+	"strings"
 
-const (
-	lDesc = "включить в набор строчные буквы"
-	uDesc = "включить в набор прописные буквы"
-	dDesc = "включить в набор цифры"
-	sDesc = "включить в набор спецсимволы"
-	cDesc = "количество символов, из которого будет состоять пароль, по умолчанию 12, максимум 64"
-	nDesc = "количество генерируемых паролей, если не заданы фразы, иначе определяется по числу фраз"
-	hDesc = "справка по программе"
+	"go-scripts/genpass/derive"
 )
 
-var (
-	// Символы в группах в порядке увеличения кодов ascii.
-	lower   = []byte("abcdefghijklmnopqrstuvwxyz")
-	upper   = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
-	digits  = []byte("0123456789")
-	symbols = []byte(" !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~")
-
-	charactersCapacity = len(lower) + len(upper) + len(digits) + len(symbols)
+const (
+	lDesc        = "включить в набор строчные буквы"
+	uDesc        = "включить в набор прописные буквы"
+	dDesc        = "включить в набор цифры"
+	sDesc        = "включить в набор спецсимволы"
+	cDesc        = "количество символов, из которого будет состоять пароль, по умолчанию 12, максимум 16384"
+	mDesc        = "минимальное количество символов каждого выбранного класса в пароле, по умолчанию 0"
+	rDesc        = "классы, к которым применяется -m: буквы l, u, d, s, по умолчанию все выбранные классы"
+	iDesc        = "дополнительные символы, которые нужно включить в набор"
+	xDesc        = "символы, которые нужно исключить из набора"
+	aDesc        = "исключить из набора неоднозначные символы " + derive.Ambiguous
+	wordsDesc    = "составить парольную фразу из указанного количества слов из списка EFF вместо пароля из символов"
+	sepDesc      = "разделитель слов в парольной фразе, по умолчанию -"
+	capsDesc     = "регистр слов в парольной фразе: none (по умолчанию), first, upper или random"
+	siteDesc     = "сгенерировать пароль сайта по профилю из файла профилей, мастер-пароль запрашивается"
+	profilesDesc = "файл профилей сайтов, по умолчанию ~/.config/genpass/sites.toml"
+	pDesc        = "запросить мастер-пароль без отображения ввода и добавить его перед каждой фразой"
+	fdDesc       = "читать мастер-пароль из первой строки указанного файлового дескриптора, а не из терминала"
+	clipDesc     = "скопировать результат в буфер обмена последовательностью OSC 52 вместо вывода"
+	pipeDesc     = "передать результат на стандартный ввод команды вместо вывода, например 'wl-copy'"
+	statsDesc    = "напечатать в стандартный поток ошибок размер набора, энтропию паролей и оценку стойкости фраз"
+	nDesc        = "количество генерируемых паролей, если не заданы фразы, иначе определяется по числу фраз"
+	hDesc        = "справка по программе"
 )
 
 func printHelp() {
@@ -45,8 +53,29 @@ func printHelp() {
 	fmt.Printf("  -s  %s\n", sDesc)
 	fmt.Printf("  -c <число>  %s\n", cDesc)
 	fmt.Println()
+	fmt.Printf("  -m <число>  %s\n", mDesc)
+	fmt.Printf("  -r <классы>  %s\n", rDesc)
+	fmt.Printf("  -i <символы>  %s\n", iDesc)
+	fmt.Printf("  -x <символы>  %s\n", xDesc)
+	fmt.Printf("  -a  %s\n", aDesc)
+	fmt.Println()
+	fmt.Printf("  -words <число>  %s\n", wordsDesc)
+	fmt.Printf("  -sep <строка>  %s\n", sepDesc)
+	fmt.Printf("  -caps <режим>  %s\n", capsDesc)
+	fmt.Println()
+	fmt.Printf("  -site <имя>  %s\n", siteDesc)
+	fmt.Printf("  -profiles <файл>  %s\n", profilesDesc)
+	fmt.Println()
+	fmt.Printf("  -p  %s\n", pDesc)
+	fmt.Printf("  -fd <число>  %s\n", fdDesc)
+	fmt.Printf("  -clip  %s\n", clipDesc)
+	fmt.Printf("  -pipe <команда>  %s\n", pipeDesc)
+	fmt.Println()
+	fmt.Printf("  -stats  %s\n", statsDesc)
 	fmt.Printf("  -n <число>  %s\n", nDesc)
 	fmt.Printf("  -h  %s\n", hDesc)
+	fmt.Println()
+	fmt.Println("Одноразовые пароли TOTP/HOTP по фразе: genpass otp [ключи] [мастер-пароль]фраза, см. genpass otp -h")
 
 	os.Exit(0)
 }
@@ -55,6 +84,10 @@ func main() {
 	if len(os.Args) == 1 {
 		printHelp()
 	}
+	if os.Args[1] == "otp" {
+		otpMain(os.Args[2:])
+		return
+	}
 
 	// Объявления ключей
 	l := flag.Bool("l", false, lDesc)
@@ -62,6 +95,21 @@ func main() {
 	d := flag.Bool("d", false, dDesc)
 	s := flag.Bool("s", false, sDesc)
 	c := flag.Int("c", 12, cDesc)
+	m := flag.Int("m", 0, mDesc)
+	r := flag.String("r", "", rDesc)
+	i := flag.String("i", "", iDesc)
+	x := flag.String("x", "", xDesc)
+	a := flag.Bool("a", false, aDesc)
+	words := flag.Int("words", 0, wordsDesc)
+	sep := flag.String("sep", "-", sepDesc)
+	caps := flag.String("caps", derive.CapsNone, capsDesc)
+	site := flag.String("site", "", siteDesc)
+	profiles := flag.String("profiles", defaultProfilesPath(), profilesDesc)
+	p := flag.Bool("p", false, pDesc)
+	fd := flag.Int("fd", -1, fdDesc)
+	clip := flag.Bool("clip", false, clipDesc)
+	pipe := flag.String("pipe", "", pipeDesc)
+	stats := flag.Bool("stats", false, statsDesc)
 	n := flag.Int("n", 1, nDesc)
 	h := flag.Bool("h", false, "Справка")
 
@@ -71,9 +119,61 @@ func main() {
 		printHelp()
 	}
 
+	phrases := flag.Args()
+
+	// Профиль сайта задаёт ключи и фразу, к которой добавляется мастер-пароль.
+	if *site != "" {
+		if len(phrases) > 0 {
+			fmt.Fprintln(os.Stderr, "С ключом -site фразы не указываются")
+			os.Exit(1)
+		}
+		phrase, err := applyProfile(*profiles, *site)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Профиль сайта: %v\n", err)
+			os.Exit(1)
+		}
+		phrases = []string{phrase}
+		*p = true
+	}
+
+	// Мастер-пароль запрашивается отдельно, чтобы он не попал в историю команд и /proc.
+	if *p || *fd >= 0 {
+		if len(phrases) == 0 {
+			fmt.Fprintln(os.Stderr, "Для мастер-пароля (-p, -fd) нужно указать фразы")
+			os.Exit(1)
+		}
+		master, err := readMaster(*fd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось прочитать мастер-пароль: %v\n", err)
+			os.Exit(1)
+		}
+		for i := range phrases {
+			phrases[i] = master + phrases[i]
+		}
+	}
+
 	// Проверка ключей
-	if *c > 64 || *c < 1 {
-		fmt.Fprintf(os.Stderr, "Количество символов (-c=%d) неправильное, должно быть от 1 до 64\n", *c)
+	if *c > derive.MaxLength || *c < 1 {
+		fmt.Fprintf(os.Stderr, "Количество символов (-c=%d) неправильное, должно быть от 1 до %d\n", *c, derive.MaxLength)
+		os.Exit(1)
+	}
+	if *m < 0 {
+		fmt.Fprintf(os.Stderr, "Минимальное количество символов класса (-m=%d) неправильное, должно быть не меньше 0\n", *m)
+		os.Exit(1)
+	}
+	if strings.Trim(*r, "luds") != "" {
+		fmt.Fprintf(os.Stderr, "Классы (-r=%s) неправильные, допустимы только буквы l, u, d, s\n", *r)
+		os.Exit(1)
+	}
+	if *words < 0 {
+		fmt.Fprintf(os.Stderr, "Количество слов (-words=%d) неправильное, должно быть не меньше 0\n", *words)
+		os.Exit(1)
+	}
+	switch *caps {
+	case derive.CapsNone, derive.CapsFirst, derive.CapsUpper, derive.CapsRandom:
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестный режим регистра (-caps=%s), должен быть %s, %s, %s или %s\n",
+			*caps, derive.CapsNone, derive.CapsFirst, derive.CapsUpper, derive.CapsRandom)
 		os.Exit(1)
 	}
 	if *n < 1 {
@@ -81,54 +181,95 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Если не указано, какие символы использовать при генерации пароля, использовать все возможные.
-	if (*l || *u || *d || *s) == false {
-		*l, *u, *d, *s = true, true, true, true
-	}
-
-	phrases := flag.Args()
-
 	// Количество паролей по числу фраз
 	if len(phrases) > 0 {
 		*n = len(phrases)
 	}
 
-	// Набор символов для генерации пароля
-	var characters = make([]byte, 0, charactersCapacity)
-	if *l {
-		characters = append(characters, lower...)
+	// Все пароли собираются в буфер и выводятся в конце, см. deliver.
+	var output bytes.Buffer
+
+	// Если фразы не заданы, то генерируются случайные пароли (из crypto/rand), иначе для каждой фразы
+	// вычисляется пароль. Парольные фразы из слов генерируются так же, как пароли: случайно или по фразе.
+	if *words > 0 {
+		opts := derive.WordOptions{Words: *words, Sep: *sep, Caps: *caps}
+		for i := 0; i < *n; i++ {
+			var passphrase string
+			var err error
+			if len(phrases) == 0 {
+				passphrase, err = derive.RandomPassphrase(nil, opts)
+			} else {
+				passphrase, err = derive.Passphrase(phrases[i], opts)
+			}
+			if err == derive.ErrExhausted {
+				fmt.Fprintf(os.Stderr, "Количество слов (-words=%d) слишком большое\n", *words)
+				os.Exit(1)
+			}
+			check(err)
+			fmt.Fprintln(&output, passphrase)
+		}
+		deliver(output.String(), *clip, *pipe)
+		if *stats {
+			printWordStats(os.Stderr, len(derive.Wordlist()), *words, *caps, phrases)
+		}
+		return
 	}
-	if *u {
-		characters = append(characters, upper...)
+
+	// Если не указано, какие символы использовать при генерации пароля, используются все возможные.
+	opts := derive.Options{
+		Length:    *c,
+		Lower:     *l,
+		Upper:     *u,
+		Digits:    *d,
+		Symbols:   *s,
+		Min:       *m,
+		Require:   *r,
+		Include:   *i,
+		Exclude:   *x,
+		Ambiguous: *a,
 	}
-	if *d {
-		characters = append(characters, digits...)
+	classes, characters, err := derive.Charset(opts)
+	check(err)
+
+	for i := 0; i < *n; i++ {
+		var password string
+		if len(phrases) == 0 {
+			password, err = derive.RandomPassword(nil, opts)
+		} else {
+			password, err = derive.Password(phrases[i], opts)
+		}
+		check(err)
+		fmt.Fprintf(&output, "%s\n", password)
 	}
-	if *s {
-		characters = append(characters, symbols...)
+
+	deliver(output.String(), *clip, *pipe)
+	if *stats {
+		printStats(os.Stderr, characters, classes, *c, phrases)
 	}
-	charactersLen := len(characters)
+}
 
-	// Единый контейнер для паролей
-	password := make([]byte, *c, *c)
+// check завершает программу с сообщением об ошибке.
+func check(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	if len(phrases) == 0 {
-		// Если не заданы фразы, то генерируются рандомные пароли.
-		rand.Seed(time.Now().UnixNano())
-		for i := 0; i < *n; i++ {
-			for i := range password {
-				password[i] = characters[rand.Intn(charactersLen)]
-			}
-			fmt.Printf("%s\n", password)
-		}
-	} else {
-		// Если фразы заданы, то для каждой фразы по определённому алгоритму вычисляется пароль.
-		for _, phrase := range phrases {
-			sum := sha512.Sum512([]byte(phrase))
-			for i := range password {
-				password[i] = characters[int(sum[i])%charactersLen]
-			}
-			fmt.Printf("%s\n", password)
-		}
+// deliver выводит пароли на стандартный вывод, копирует их в буфер обмена (clip)
+// или передаёт команде (pipe).
+func deliver(text string, clip bool, pipe string) {
+	var err error
+	switch {
+	case clip:
+		err = copyOSC52(strings.TrimSuffix(text, "\n"))
+	case pipe != "":
+		err = pipeTo(pipe, text)
+	default:
+		_, err = os.Stdout.WriteString(text)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось вывести пароль: %v\n", err)
+		os.Exit(1)
 	}
 }
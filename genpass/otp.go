@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go-scripts/genpass/derive"
+)
+
+const (
+	otpUriDesc     = "напечатать секрет в Base32 и ссылку otpauth:// для добавления в приложение"
+	otpHotpDesc    = "вычислить код HOTP для указанного счётчика вместо кода TOTP"
+	otpDigitsDesc  = "количество цифр кода, по умолчанию 6"
+	otpPeriodDesc  = "длительность шага TOTP в секундах, по умолчанию 30"
+	otpAlgDesc     = "алгоритм HMAC: SHA1 (по умолчанию), SHA256 или SHA512"
+	otpSizeDesc    = "размер секрета в байтах, по умолчанию 20"
+	otpIssuerDesc  = "название сервиса в ссылке otpauth://"
+	otpAccountDesc = "имя учётной записи в ссылке otpauth://, по умолчанию фраза, если мастер-пароль введён отдельно (-p, -fd)"
+)
+
+func printOTPHelp() {
+	fmt.Println("Использование: genpass otp [ключи] [мастер-пароль]фраза")
+	fmt.Println("Вычислить по фразе секрет для одноразовых паролей и напечатать текущий код TOTP (RFC 6238)")
+	fmt.Println("или код HOTP (RFC 4226). Секрет однозначно определяется фразой, как и пароль.")
+	fmt.Println("Ключи:")
+	fmt.Printf("  -uri  %s\n", otpUriDesc)
+	fmt.Printf("  -hotp <число>  %s\n", otpHotpDesc)
+	fmt.Printf("  -digits <число>  %s\n", otpDigitsDesc)
+	fmt.Printf("  -period <число>  %s\n", otpPeriodDesc)
+	fmt.Printf("  -alg <алгоритм>  %s\n", otpAlgDesc)
+	fmt.Printf("  -size <число>  %s\n", otpSizeDesc)
+	fmt.Printf("  -issuer <строка>  %s\n", otpIssuerDesc)
+	fmt.Printf("  -account <строка>  %s\n", otpAccountDesc)
+	fmt.Println()
+	fmt.Printf("  -p  %s\n", pDesc)
+	fmt.Printf("  -fd <число>  %s\n", fdDesc)
+	fmt.Printf("  -h  %s\n", hDesc)
+
+	os.Exit(0)
+}
+
+// otpMain выполняет подкоманду genpass otp.
+func otpMain(args []string) {
+	fs := flag.NewFlagSet("genpass otp", flag.ExitOnError)
+	uri := fs.Bool("uri", false, otpUriDesc)
+	hotp := fs.Int64("hotp", -1, otpHotpDesc)
+	digits := fs.Int("digits", 6, otpDigitsDesc)
+	period := fs.Int("period", 30, otpPeriodDesc)
+	alg := fs.String("alg", "SHA1", otpAlgDesc)
+	size := fs.Int("size", 20, otpSizeDesc)
+	issuer := fs.String("issuer", "", otpIssuerDesc)
+	account := fs.String("account", "", otpAccountDesc)
+	p := fs.Bool("p", false, pDesc)
+	fd := fs.Int("fd", -1, fdDesc)
+	h := fs.Bool("h", false, hDesc)
+
+	fs.Parse(args)
+
+	if *h || fs.NArg() != 1 {
+		printOTPHelp()
+	}
+
+	// Без -p и -fd аргумент содержит мастер-пароль, поэтому он не может быть именем учётной записи.
+	phrase := fs.Arg(0)
+	if *p || *fd >= 0 {
+		if *account == "" {
+			*account = phrase
+		}
+		master, err := readMaster(*fd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось прочитать мастер-пароль: %v\n", err)
+			os.Exit(1)
+		}
+		phrase = master + phrase
+	}
+
+	if *uri && *account == "" {
+		fmt.Fprintln(os.Stderr, "Для ссылки otpauth:// нужно указать -account или ввести мастер-пароль отдельно (-p, -fd)")
+		os.Exit(1)
+	}
+
+	secret, err := derive.OTPSecret(phrase, *size)
+	check(err)
+	otp := derive.OTP{Secret: secret, Algorithm: *alg, Digits: *digits, Period: *period}
+
+	if *uri {
+		fmt.Printf("Секрет: %s\n", otp.Base32())
+		fmt.Println(otp.URI(*issuer, *account, *hotp >= 0, uint64(max(*hotp, 0))))
+	}
+
+	var code string
+	if *hotp >= 0 {
+		code, err = otp.HOTP(uint64(*hotp))
+	} else {
+		code, err = otp.TOTP(time.Now())
+	}
+	check(err)
+	fmt.Println(code)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+/*
+Профили сайтов хранятся в файле TOML, где каждому сайту соответствует таблица, например:
+
+	[github]
+	length = 20
+	classes = "luds"
+	min = 1
+	require = "ds"
+	exclude = "Il1O0"
+	counter = 2
+
+Ключи профиля задают значения ключей командной строки (см. profileFlags), а ключи, указанные явно
+в командной строке, имеют приоритет над профилем. Кроме того, в профиле можно указать:
+  - classes - классы символов (буквы l, u, d, s), аналог -l -u -d -s;
+  - phrase - фраза, которая добавляется к мастер-паролю, по умолчанию имя сайта;
+  - counter - номер пароля; увеличение номера меняет пароль, не меняя мастер-пароль.
+*/
+
+// Соответствие ключей профиля ключам командной строки.
+var profileFlags = map[string]string{
+	"length":    "c",
+	"min":       "m",
+	"require":   "r",
+	"include":   "i",
+	"exclude":   "x",
+	"ambiguous": "a",
+	"words":     "words",
+	"sep":       "sep",
+	"caps":      "caps",
+}
+
+// Разделитель фразы и номера пароля.
+const counterSep = '\x00'
+
+// defaultProfilesPath возвращает путь к файлу профилей по умолчанию: ~/.config/genpass/sites.toml.
+func defaultProfilesPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "sites.toml"
+	}
+	return filepath.Join(dir, "genpass", "sites.toml")
+}
+
+// applyProfile загружает профиль сайта site из файла path, устанавливает ключи командной строки,
+// не указанные явно, и возвращает фразу сайта (без мастер-пароля).
+func applyProfile(path, site string) (string, error) {
+	var sites map[string]map[string]any
+	if _, err := toml.DecodeFile(path, &sites); err != nil {
+		return "", err
+	}
+	profile, ok := sites[site]
+	if !ok {
+		return "", fmt.Errorf("сайт %q не найден в %s", site, path)
+	}
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	phrase := site
+	counter := int64(1)
+	for key, value := range profile {
+		switch key {
+		case "phrase":
+			s, ok := value.(string)
+			if !ok || strings.ContainsRune(s, counterSep) {
+				return "", fmt.Errorf("%s: %s.phrase должен быть строкой без нулевых символов", path, site)
+			}
+			phrase = s
+		case "counter":
+			n, ok := value.(int64)
+			if !ok || n < 1 {
+				return "", fmt.Errorf("%s: %s.counter должен быть целым числом больше 0", path, site)
+			}
+			counter = n
+		case "classes":
+			s, ok := value.(string)
+			if !ok || s == "" || strings.Trim(s, "luds") != "" {
+				return "", fmt.Errorf("%s: %s.classes должен состоять из букв l, u, d, s", path, site)
+			}
+			if explicit["l"] || explicit["u"] || explicit["d"] || explicit["s"] {
+				continue
+			}
+			for _, letter := range s {
+				if err := flag.Set(string(letter), "true"); err != nil {
+					return "", fmt.Errorf("%s: %s.classes: класс %c: %v", path, site, letter, err)
+				}
+			}
+		default:
+			name, ok := profileFlags[key]
+			if !ok {
+				return "", fmt.Errorf("%s: неизвестный ключ %s.%s", path, site, key)
+			}
+			if explicit[name] {
+				continue
+			}
+			if err := flag.Set(name, fmt.Sprint(value)); err != nil {
+				return "", fmt.Errorf("%s: %s.%s: %v", path, site, key, err)
+			}
+		}
+	}
+
+	// Первый пароль сайта совпадает с паролем по фразе '<мастер-пароль><фраза>'. Номер отделяется
+	// нулевым символом, который не может быть в аргументах командной строки, поэтому пароль с номером
+	// не совпадает с паролем ни по какой фразе.
+	if counter > 1 {
+		phrase += string(counterSep) + strconv.FormatInt(counter, 10)
+	}
+	return phrase, nil
+}
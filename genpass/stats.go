@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"go-scripts/genpass/derive"
+)
+
+// Предел количества операций при точном вычислении энтропии с учётом -m.
+const maxPolicyWork = 1 << 27
+
+// Самые распространённые пароли в порядке популярности, используются при оценке фраз.
+var commonPasswords = []string{
+	"123456", "password", "123456789", "12345678", "12345", "qwerty", "1234567", "111111", "1234567890",
+	"123123", "abc123", "1234", "password1", "iloveyou", "1q2w3e4r", "000000", "qwerty123", "zaq12wsx",
+	"dragon", "sunshine", "princess", "letmein", "654321", "monkey", "qazwsx", "1qaz2wsx", "123321",
+	"qwertyuiop", "superman", "asdfghjkl", "master", "admin", "welcome", "login", "football", "secret",
+}
+
+// Ряды клавиатуры для поиска последовательностей соседних клавиш.
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "йцукенгшщзхъ", "фывапролджэ", "ячсмитьбю"}
+
+// group - символы одного класса, для которых вычисляется энтропия.
+type group struct {
+	p   float64 // вероятность, что символ пароля принадлежит группе
+	h   float64 // вклад группы в энтропию символа: сумма -p*log2(p) по символам группы
+	min int     // требуемое количество символов группы
+}
+
+// charGroups разбивает набор символов на группы по классам. probs - вероятности символов набора.
+// Символы, не входящие в классы (из -i), объединяются в группу без требований.
+func charGroups(characters []byte, probs []float64, classes []derive.Class) []group {
+	groups := make([]group, len(classes)+1)
+	for i, cl := range classes {
+		groups[i].min = cl.Min
+	}
+	for k, char := range characters {
+		i := len(classes)
+		for j, cl := range classes {
+			if strings.IndexByte(string(cl.Chars), char) != -1 {
+				i = j
+				break
+			}
+		}
+		groups[i].p += probs[k]
+		if probs[k] > 0 {
+			groups[i].h -= probs[k] * math.Log2(probs[k])
+		}
+	}
+	return groups
+}
+
+// policyEntropy вычисляет энтропию пароля длиной length при условии, что выполнены требования групп.
+// Пароли, не удовлетворяющие требованиям, отбрасываются, поэтому распределение паролей - это исходное
+// распределение при условии A (выполнения требований), и H = E_A/P(A) + log2 P(A),
+// где E_A - сумма -p(x)*log2 p(x) по паролям x из A. E_A и P(A) вычисляются динамикой по позициям,
+// где состояние - количество уже набранных символов каждой группы (не больше требуемого).
+// Возвращает false, если вычисление слишком трудоёмкое.
+func policyEntropy(length int, groups []group) (float64, bool) {
+	states := 1
+	for _, g := range groups {
+		states *= g.min + 1
+		if states*length > maxPolicyWork {
+			return 0, false
+		}
+	}
+	prob := make([]float64, states)
+	ent := make([]float64, states)
+	nextProb := make([]float64, states)
+	nextEnt := make([]float64, states)
+	prob[0] = 1
+	for i := 0; i < length; i++ {
+		clear(nextProb)
+		clear(nextEnt)
+		for state := range prob {
+			if prob[state] == 0 {
+				continue
+			}
+			rest, radix := state, 1
+			for _, g := range groups {
+				next := state
+				if rest%(g.min+1) < g.min {
+					next += radix
+				}
+				nextProb[next] += prob[state] * g.p
+				nextEnt[next] += ent[state]*g.p + prob[state]*g.h
+				rest /= g.min + 1
+				radix *= g.min + 1
+			}
+		}
+		prob, nextProb = nextProb, prob
+		ent, nextEnt = nextEnt, ent
+	}
+	accepted := prob[states-1]
+	return ent[states-1]/accepted + math.Log2(accepted), true
+}
+
+// byteProbs возвращает вероятности символов набора из n символов при отображении равномерно
+// распределённого байта b в символ с индексом b % n: первые 256 % n символов выпадают чаще.
+func byteProbs(n int) []float64 {
+	probs := make([]float64, n)
+	for k := range probs {
+		count := 256 / n
+		if k < 256%n {
+			count++
+		}
+		probs[k] = float64(count) / 256
+	}
+	return probs
+}
+
+func uniformProbs(n int) []float64 {
+	probs := make([]float64, n)
+	for k := range probs {
+		probs[k] = 1 / float64(n)
+	}
+	return probs
+}
+
+// printEntropy печатает энтропию пароля для заданных вероятностей символов.
+func printEntropy(w io.Writer, title string, characters []byte, probs []float64, classes []derive.Class, length int) {
+	groups := charGroups(characters, probs, classes)
+	perChar := 0.0
+	for _, g := range groups {
+		perChar += g.h
+	}
+	fmt.Fprintf(w, "  %s: %.2f бит (%.4f бит на символ)\n", title, perChar*float64(length), perChar)
+	hasPolicy := false
+	for _, g := range groups {
+		hasPolicy = hasPolicy || g.min > 0
+	}
+	if !hasPolicy {
+		return
+	}
+	if bits, ok := policyEntropy(length, groups); ok {
+		fmt.Fprintf(w, "  %s, требование -m: %.2f бит\n", title, bits)
+	} else {
+		fmt.Fprintf(w, "  %s, требование -m: слишком долго вычислять\n", title)
+	}
+}
+
+// printStats печатает статистику паролей из символов. Если phrases пустой, пароли случайные: энтропия
+// случайного пароля равна теоретической только потому, что генератор не ограничен зерном (см. derive.RandomPassword).
+func printStats(w io.Writer, characters []byte, classes []derive.Class, length int, phrases []string) {
+	n := len(characters)
+	fmt.Fprintln(w, "Статистика:")
+	fmt.Fprintf(w, "  размер набора символов: %d\n", n)
+	fmt.Fprintf(w, "  длина пароля: %d\n", length)
+	printEntropy(w, "теоретическая энтропия", characters, uniformProbs(n), classes, length)
+	if len(phrases) == 0 {
+		fmt.Fprintln(w, "  случайные пароли: символы выбираются равновероятно генератором crypto/rand, смещения нет")
+		return
+	}
+	if 256%n == 0 {
+		fmt.Fprintf(w, "  отображение байт %% %d равновероятно, смещения нет\n", n)
+	} else {
+		fmt.Fprintf(w, "  отображение байт %% %d: %d символов выпадают с вероятностью %d/256, остальные %d/256\n",
+			n, 256%n, 256/n+1, 256/n)
+		printEntropy(w, "энтропия с учётом смещения", characters, byteProbs(n), classes, length)
+	}
+	printPhraseStats(w, phrases)
+}
+
+// printWordStats печатает статистику парольных фраз из слов. Слова выбираются равновероятно.
+func printWordStats(w io.Writer, listLen int, words int, caps string, phrases []string) {
+	perWord := math.Log2(float64(listLen))
+	if caps == derive.CapsRandom {
+		perWord++
+	}
+	fmt.Fprintln(w, "Статистика:")
+	fmt.Fprintf(w, "  размер списка слов: %d\n", listLen)
+	fmt.Fprintf(w, "  количество слов: %d\n", words)
+	fmt.Fprintf(w, "  энтропия: %.2f бит (%.4f бит на слово)\n", perWord*float64(words), perWord)
+	if len(phrases) == 0 {
+		fmt.Fprintln(w, "  случайные фразы: слова выбираются равновероятно генератором crypto/rand")
+	}
+	printPhraseStats(w, phrases)
+}
+
+// printPhraseStats печатает оценку стойкости фраз. Сами фразы не печатаются, так как могут содержать
+// мастер-пароль. Пароль, вычисленный по фразе, нельзя подобрать быстрее, чем саму фразу.
+func printPhraseStats(w io.Writer, phrases []string) {
+	for i, phrase := range phrases {
+		bits := estimateGuesses(phrase)
+		fmt.Fprintf(w, "  фраза %d: длина %d, оценка стойкости %.2f бит (~10^%.1f попыток)\n",
+			i+1, len([]rune(phrase)), bits, bits*math.Log10(2))
+	}
+}
+
+// estimateGuesses оценивает в битах количество попыток, за которое можно подобрать фразу.
+// Это упрощённый вариант алгоритма zxcvbn: фраза разбивается на фрагменты (словарные слова,
+// распространённые пароли, повторы, последовательности, соседние клавиши, годы), и выбирается разбиение
+// с минимальным произведением количества попыток. Символы вне фрагментов подбираются перебором.
+func estimateGuesses(phrase string) float64 {
+	runes := []rune(phrase)
+	lower := []rune(strings.ToLower(phrase))
+	n := len(runes)
+	if len(lower) != n {
+		lower = runes
+	}
+	dictionary := make(map[string]float64, len(commonPasswords)+7776)
+	for _, word := range derive.Wordlist() {
+		dictionary[word] = 7776
+	}
+	for rank, password := range commonPasswords {
+		dictionary[password] = float64(rank + 1)
+	}
+
+	// best[i] - минимальный log2 количества попыток для первых i символов.
+	best := make([]float64, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = best[i-1] + math.Log2(cardinality(runes[i-1]))
+		for j := max(0, i-32); j <= i-3; j++ {
+			if guesses := fragmentGuesses(runes[j:i], lower[j:i], dictionary); guesses > 0 {
+				best[i] = math.Min(best[i], best[j]+math.Log2(guesses))
+			}
+		}
+	}
+	return best[n]
+}
+
+// fragmentGuesses возвращает количество попыток для фрагмента или 0, если фрагмент не подходит
+// ни под один шаблон.
+func fragmentGuesses(runes, lower []rune, dictionary map[string]float64) float64 {
+	n := len(runes)
+	guesses := 0.0
+	better := func(g float64) {
+		if g > 0 && (guesses == 0 || g < guesses) {
+			guesses = g
+		}
+	}
+
+	// Словарное слово или распространённый пароль с учётом вариантов регистра.
+	if rank, ok := dictionary[string(lower)]; ok {
+		upper := 0
+		for _, r := range runes {
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+		switch {
+		case upper == 0:
+			better(rank)
+		case upper == n || (upper == 1 && unicode.IsUpper(runes[0])):
+			better(rank * 2)
+		default:
+			better(rank * math.Pow(2, float64(upper)))
+		}
+	}
+
+	// Повтор одного символа.
+	repeat := true
+	for _, r := range lower[1:] {
+		repeat = repeat && r == lower[0]
+	}
+	if repeat {
+		better(cardinality(runes[0]) * float64(n))
+	}
+
+	// Последовательность с постоянным шагом 1 или -1, например abc, 987.
+	delta := lower[1] - lower[0]
+	sequence := delta == 1 || delta == -1
+	for i := 2; i < n && sequence; i++ {
+		sequence = lower[i]-lower[i-1] == delta
+	}
+	if sequence {
+		base := cardinality(lower[0])
+		if strings.ContainsRune("aа01", lower[0]) {
+			base = 4
+		}
+		if delta < 0 {
+			base *= 2
+		}
+		better(base * float64(n))
+	}
+
+	// Соседние клавиши в одном ряду клавиатуры в прямом или обратном порядке.
+	fragment := string(lower)
+	reversed := make([]rune, n)
+	for i, r := range lower {
+		reversed[n-1-i] = r
+	}
+	for _, row := range keyboardRows {
+		if strings.Contains(row, fragment) || strings.Contains(row, string(reversed)) {
+			better(float64(len(keyboardRows)) * float64(len([]rune(row))) * float64(n))
+		}
+	}
+
+	// Год: попыток столько, сколько лет отделяет его от текущего, но не меньше 20.
+	if n == 4 {
+		year := 0
+		for _, r := range runes {
+			if r < '0' || r > '9' {
+				year = -1
+				break
+			}
+			year = year*10 + int(r-'0')
+		}
+		if year >= 1900 && year <= 2099 {
+			better(math.Max(math.Abs(float64(year-time.Now().Year())), 20))
+		}
+	}
+
+	return guesses
+}
+
+// cardinality возвращает размер класса символа r для перебора.
+func cardinality(r rune) float64 {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case r >= '0' && r <= '9':
+		return 10
+	case r < 128:
+		return 33
+	case unicode.IsLetter(r):
+		return 33 // например, кириллица
+	}
+	return 100
+}
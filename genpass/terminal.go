@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// readMaster запрашивает мастер-пароль. Если fd >= 0, то мастер-пароль читается из первой строки
+// файлового дескриптора fd (для скриптов), иначе из терминала без отображения ввода.
+// Если стандартный ввод не терминал, то мастер-пароль читается из первой строки стандартного ввода.
+func readMaster(fd int) (string, error) {
+	if fd >= 0 {
+		return readLine(os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd)))
+	}
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return readLine(os.Stdin)
+	}
+	fmt.Fprint(os.Stderr, "Мастер-пароль: ")
+	master, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(master), nil
+}
+
+func readLine(r io.Reader) (string, error) {
+	if r == nil {
+		return "", os.ErrInvalid
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// copyOSC52 копирует text в буфер обмена с помощью управляющей последовательности OSC 52,
+// которую понимают многие эмуляторы терминала, в том числе через ssh и tmux.
+// Последовательность пишется прямо в терминал, чтобы пароль не попал в перенаправленный вывод.
+func copyOSC52(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// pipeTo передаёт text на стандартный ввод команды command, выполняемой через sh -c,
+// например 'xclip -selection clipboard' или 'wl-copy'.
+func pipeTo(command string, text string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
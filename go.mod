@@ -1,3 +1,15 @@
 module go-scripts
 
-go 1.22.0
+go 1.25.0
+
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/PuerkitoBio/goquery v1.13.0
+	golang.org/x/term v0.45.0
+)
+
+require (
+	github.com/andybalholm/cascadia v1.3.4 // indirect
+	golang.org/x/net v0.58.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+)
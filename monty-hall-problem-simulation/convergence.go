@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Number of points of the convergence series, spaced logarithmically.
+const convergencePoints = 200
+
+// series is the running success ratio of a strategy with a host.
+type series struct {
+	host      string
+	strategy  string
+	expected  float64 // 0 if unknown
+	trials    []int   // checkpoints
+	successes []int   // cumulative successes at the checkpoints
+}
+
+// checkpoints returns logarithmically spaced trial counts from 1 to n.
+func checkpoints(n int) []int {
+	var points []int
+	for i := 0; i < convergencePoints; i++ {
+		t := int(math.Round(math.Pow(float64(n), float64(i)/(convergencePoints-1))))
+		if len(points) == 0 || t > points[len(points)-1] {
+			points = append(points, t)
+		}
+	}
+	return points
+}
+
+// writeCsv writes all series in long format, one row per series and checkpoint.
+func writeCsv(w io.Writer, allSeries []series) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"host", "strategy", "trials", "successes", "ratio", "ci_low", "ci_high", "expected"})
+	for _, s := range allSeries {
+		for i, t := range s.trials {
+			low, high := confidenceInterval(s.successes[i], t)
+			expected := ""
+			if s.expected > 0 {
+				expected = formatFloat(s.expected)
+			}
+			writer.Write([]string{s.host, s.strategy, strconv.Itoa(t), strconv.Itoa(s.successes[i]),
+				formatFloat(float64(s.successes[i]) / float64(t)), formatFloat(low), formatFloat(high), expected})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
+
+// Chart geometry.
+const (
+	chartWidth   = 900
+	chartHeight  = 500
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 40
+	marginBottom = 50
+	legendHeight = 18
+)
+
+var seriesColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+// writeSvg draws the running success ratios on a logarithmic trials axis with 95% confidence bands
+// and dashed lines of the expected ratios.
+func writeSvg(w io.Writer, allSeries []series, n int) error {
+	height := chartHeight + legendHeight*len(allSeries)
+	plotWidth := float64(chartWidth - marginLeft - marginRight)
+	plotHeight := float64(chartHeight - marginTop - marginBottom)
+	logN := math.Log10(float64(max(n, 10)))
+	x := func(t int) float64 {
+		return marginLeft + math.Log10(float64(t))/logN*plotWidth
+	}
+	y := func(ratio float64) float64 {
+		return marginTop + (1-ratio)*plotHeight
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", chartWidth, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="24" font-size="16">Running success ratio</text>`+"\n", marginLeft)
+
+	// Grid and axes.
+	for _, ratio := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", marginLeft, y(ratio), chartWidth-marginRight, y(ratio))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%.2f</text>`+"\n", marginLeft-6, y(ratio), ratio)
+	}
+	for t := 1; t <= n; t *= 10 {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n", x(t), marginTop, x(t), chartHeight-marginBottom)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%d</text>`+"\n", x(t), chartHeight-marginBottom+16, t)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">Trials</text>`+"\n", marginLeft+plotWidth/2, chartHeight-marginBottom+36)
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%.1f" fill="none" stroke="black"/>`+"\n", marginLeft, marginTop, plotWidth, plotHeight)
+
+	// Expected ratios.
+	var expected []float64
+	for _, s := range allSeries {
+		if s.expected > 0 && !slices.Contains(expected, s.expected) {
+			expected = append(expected, s.expected)
+		}
+	}
+	for _, e := range expected {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="black" stroke-dasharray="6,4"/>`+"\n", marginLeft, y(e), chartWidth-marginRight, y(e))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%.4f</text>`+"\n", chartWidth-marginRight-4, y(e)-4, e)
+	}
+
+	for i, s := range allSeries {
+		color := seriesColors[i%len(seriesColors)]
+		var band, line []string
+		for j, t := range s.trials {
+			ratio := float64(s.successes[j]) / float64(t)
+			_, high := confidenceInterval(s.successes[j], t)
+			band = append(band, fmt.Sprintf("%.1f,%.1f", x(t), y(high)))
+			line = append(line, fmt.Sprintf("%.1f,%.1f", x(t), y(ratio)))
+		}
+		for j := len(s.trials) - 1; j >= 0; j-- {
+			low, _ := confidenceInterval(s.successes[j], s.trials[j])
+			band = append(band, fmt.Sprintf("%.1f,%.1f", x(s.trials[j]), y(low)))
+		}
+		fmt.Fprintf(&b, `<polygon points="%s" fill="%s" fill-opacity="0.2" stroke="none"/>`+"\n", strings.Join(band, " "), color)
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n", strings.Join(line, " "), color)
+
+		legendY := chartHeight + legendHeight*i
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="24" height="10" fill="%s"/>`+"\n", marginLeft, legendY-5, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" dominant-baseline="middle">%s, %s (95%% confidence band)</text>`+"\n",
+			marginLeft+32, legendY, xmlEscape(s.strategy), xmlEscape(s.host))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Number of games simulated to find the best strategy with the host.
+const optimalTrials = 1 << 16
+
+// scoreboard counts results of the session.
+type scoreboard struct {
+	player, stayed, switched, optimal stats
+}
+
+// optimalStrategy returns the strategy (stay or switch) with the best success ratio with the host.
+func optimalStrategy(rnd *rand.Rand, host Host) int {
+	best, bestSuccesses := 0, -1
+	for i, strategy := range strategies {
+		if strategy.key == "random" {
+			continue
+		}
+		successes := 0
+		for j := 0; j < optimalTrials; j++ {
+			success, _ := strategy.play(rnd, host)
+			successes += success
+		}
+		if successes > bestSuccesses {
+			best, bestSuccesses = i, successes
+		}
+	}
+	return best
+}
+
+func printDoors(w io.Writer, answerIndex int, openedIndexes []int, successIndex int, reveal bool) {
+	for i := 0; i < *doors; i++ {
+		label := strconv.Itoa(i + 1)
+		switch {
+		case reveal && i == successIndex || slices.Contains(openedIndexes, i) && i == successIndex:
+			label = "car"
+		case reveal || slices.Contains(openedIndexes, i):
+			label = "goat"
+		}
+		if i == answerIndex {
+			label = "*" + label + "*"
+		}
+		fmt.Fprintf(w, "[%s] ", label)
+	}
+	fmt.Fprintln(w)
+}
+
+// readDoor reads door number from the input until it is one of the allowed doors. Returns false on quit
+// or end of input.
+func readDoor(scanner *bufio.Scanner, w io.Writer, prompt string, allowed []int) (int, bool) {
+	for {
+		fmt.Fprint(w, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return 0, false
+		}
+		text := strings.TrimSpace(scanner.Text())
+		if text == "q" {
+			return 0, false
+		}
+		door, err := strconv.Atoi(text)
+		if err == nil && slices.Contains(allowed, door-1) {
+			return door - 1, true
+		}
+		fmt.Fprintf(w, "Enter one of the doors %v or q to quit.\n", doorNumbers(allowed))
+	}
+}
+
+func doorNumbers(indexes []int) []int {
+	numbers := make([]int, len(indexes))
+	for i, index := range indexes {
+		numbers[i] = index + 1
+	}
+	return numbers
+}
+
+func ratio(s stats) string {
+	if s.games == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(s.successCount)/float64(s.games))
+}
+
+// game runs the interactive game with the host until the player quits.
+func game(r io.Reader, w io.Writer, rnd *rand.Rand, host Host, hostName string) {
+	optimal := strategies[optimalStrategy(rnd, host)]
+	fmt.Fprintf(w, "Doors: %d, the car is behind one of them, goats are behind the others.\n", *doors)
+	fmt.Fprintf(w, "Host: %s. Pick a door, the host opens some of the others, then stay or switch. Enter q to quit.\n", hostName)
+
+	allIndexes := otherIndexes(-1) // no door is excluded
+	scanner := bufio.NewScanner(r)
+	var score scoreboard
+	for round := 1; ; round++ {
+		fmt.Fprintf(w, "\nRound %d\n", round)
+		successIndex := rnd.Intn(*doors)
+		printDoors(w, -1, nil, successIndex, false)
+		answerIndex, ok := readDoor(scanner, w, "Your door: ", allIndexes)
+		if !ok {
+			break
+		}
+
+		openedIndexes, _ := host.Open(rnd, successIndex, answerIndex)
+		answer2Index := answerIndex
+		if len(openedIndexes) == 0 {
+			fmt.Fprintln(w, "The host does not offer to switch.")
+		} else {
+			fmt.Fprintf(w, "The host opens %v.\n", doorNumbers(openedIndexes))
+			printDoors(w, answerIndex, openedIndexes, successIndex, false)
+			closed := filter(allIndexes, func(v int) bool { return !slices.Contains(openedIndexes, v) })
+			answer2Index, ok = readDoor(scanner, w, fmt.Sprintf("Stay with %d or switch to %v: ", answerIndex+1, doorNumbers(filter(closed, func(v int) bool { return v != answerIndex }))), closed)
+			if !ok {
+				break
+			}
+		}
+
+		printDoors(w, answer2Index, openedIndexes, successIndex, true)
+		success := 0
+		if answer2Index == successIndex {
+			success = 1
+			fmt.Fprintln(w, "You win the car!")
+		} else {
+			fmt.Fprintf(w, "You get a goat, the car was behind door %d.\n", successIndex+1)
+		}
+		score.player.add(success)
+		if answer2Index == answerIndex {
+			score.stayed.add(success)
+		} else {
+			score.switched.add(success)
+		}
+		optimalSuccess, _ := optimal.play(rnd, host)
+		score.optimal.add(optimalSuccess)
+
+		fmt.Fprintf(w, "Score: you %d/%d (%s): stayed %d/%d (%s), switched %d/%d (%s); simulated %q %d/%d (%s)\n",
+			score.player.successCount, score.player.games, ratio(score.player),
+			score.stayed.successCount, score.stayed.games, ratio(score.stayed),
+			score.switched.successCount, score.switched.games, ratio(score.switched),
+			strings.ToLower(optimal.name), score.optimal.successCount, score.optimal.games, ratio(score.optimal))
+	}
+
+	if score.player.games > 0 {
+		fmt.Fprintf(w, "\nFinal score: you won %d of %d games (%s), the simulated %q strategy won %d (%s).\n",
+			score.player.successCount, score.player.games, ratio(score.player),
+			strings.ToLower(optimal.name), score.optimal.successCount, ratio(score.optimal))
+	}
+}
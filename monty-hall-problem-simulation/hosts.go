@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// Host decides which doors to open after the first answer.
+type Host interface {
+	// Open returns the doors the host opens and the condition of the game the win rates are reported for.
+	// No opened doors means the host does not offer to switch.
+	Open(rnd *rand.Rand, successIndex, answerIndex int) (openedIndexes []int, condition string)
+}
+
+// otherIndexes returns the doors except the answer, in increasing order.
+func otherIndexes(answerIndex int) []int {
+	indexes := make([]int, 0, *doors-1)
+	for i := 0; i < *doors; i++ {
+		if i != answerIndex {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func randomSubset(rnd *rand.Rand, indexes []int, n int) []int {
+	indexes = slices.Clone(indexes)
+	rnd.Shuffle(len(indexes), func(i, j int) {
+		indexes[i], indexes[j] = indexes[j], indexes[i]
+	})
+	return indexes[:n]
+}
+
+// standardHost knows where the car is and always opens random goat doors.
+type standardHost struct{}
+
+func (standardHost) Open(rnd *rand.Rand, successIndex, answerIndex int) ([]int, string) {
+	otherFailIndexes := filter(otherIndexes(answerIndex), func(v int) bool { return v != successIndex })
+	return randomSubset(rnd, otherFailIndexes, *opened), "switch offered"
+}
+
+// ignorantHost (Monty Fall) does not know where the car is and opens random doors, so he may reveal the car.
+type ignorantHost struct{}
+
+func (ignorantHost) Open(rnd *rand.Rand, successIndex, answerIndex int) ([]int, string) {
+	openedIndexes := randomSubset(rnd, otherIndexes(answerIndex), *opened)
+	if slices.Contains(openedIndexes, successIndex) {
+		return openedIndexes, "car revealed"
+	}
+	return openedIndexes, "goats revealed"
+}
+
+// preferringHost (Monty Crawl) knows where the car is and opens goat doors with the lowest numbers.
+// If he opens doors other than the preferred ones, he has to avoid the car behind a preferred door.
+type preferringHost struct{}
+
+func (preferringHost) Open(rnd *rand.Rand, successIndex, answerIndex int) ([]int, string) {
+	others := otherIndexes(answerIndex)
+	otherFailIndexes := filter(others, func(v int) bool { return v != successIndex })
+	openedIndexes := otherFailIndexes[:*opened]
+	if slices.Equal(openedIndexes, others[:*opened]) {
+		return openedIndexes, "preferred doors opened"
+	}
+	return openedIndexes, "other doors opened"
+}
+
+// devilHost (Monty from Hell) offers to switch only when the first answer is the car.
+type devilHost struct{}
+
+func (devilHost) Open(rnd *rand.Rand, successIndex, answerIndex int) ([]int, string) {
+	if answerIndex != successIndex {
+		return nil, "switch not offered"
+	}
+	openedIndexes, _ := standardHost{}.Open(rnd, successIndex, answerIndex)
+	return openedIndexes, "switch offered"
+}
+
+// angelHost offers to switch only when the first answer is a goat.
+type angelHost struct{}
+
+func (angelHost) Open(rnd *rand.Rand, successIndex, answerIndex int) ([]int, string) {
+	if answerIndex == successIndex {
+		return nil, "switch not offered"
+	}
+	openedIndexes, _ := standardHost{}.Open(rnd, successIndex, answerIndex)
+	return openedIndexes, "switch offered"
+}
+
+var hosts = []struct {
+	key  string
+	name string
+	host Host
+}{
+	{"standard", "standard", standardHost{}},
+	{"ignorant", "ignorant (Monty Fall)", ignorantHost{}},
+	{"preferring", "preferring lower doors (Monty Crawl)", preferringHost{}},
+	{"devil", "devil (Monty from Hell)", devilHost{}},
+	{"angel", "angel", angelHost{}},
+}
@@ -1,16 +1,38 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
-const DEBUG = false
-const N = 1 << 20
+// Trials are split into chunks of this size, each chunk has its own random generator seeded from the seed
+// and the chunk number (see chunkSeed), so results depend only on the seed, not on the number of workers.
+const chunkSize = 1 << 14
+
+// z-score of the 95% confidence level
+const Z = 1.96
 
 var (
-	successCount int
-	failCount    int
+	strategyNames = flag.String("strategy", "stay,switch", "comma-separated `strategies`: random (no host), stay, switch, or all")
+	hostNames     = flag.String("host", "all", "comma-separated `hosts`: standard, ignorant, preferring, devil, angel, or all")
+	trials        = flag.Int("n", 1<<20, "number of trials per host and strategy")
+	seed          = flag.Int64("seed", 0, "random seed, by default derived from the current time; the same seed gives the same results")
+	workers       = flag.Int("workers", runtime.NumCPU(), "number of goroutines running trials")
+	doors         = flag.Int("doors", 3, "number of doors, the car is behind one of them")
+	opened        = flag.Int("opened", 1, "number of doors the host opens, at most doors-2")
+	debugFlag     = flag.Bool("debug", false, "print every game, implies -workers 1")
+	csvFile       = flag.String("csv", "", "write the running success ratio of each host and strategy to CSV `file`")
+	playFlag      = flag.Bool("play", false, "play the game interactively with the host given by -host (standard by default)")
+	svgFile       = flag.String("svg", "", "draw the running success ratio of each host and strategy to SVG `file`")
 )
 
 func filter(slice []int, f func(int) bool) []int {
@@ -24,93 +46,309 @@ func filter(slice []int, f func(int) bool) []int {
 }
 
 func debug(format string, args ...interface{}) {
-	if DEBUG {
+	if *debugFlag {
 		fmt.Printf(format, args...)
 	}
 }
 
-func noSimulation() {
-	for i := 0; i < N; i++ {
-		successIndex := rand.Intn(3)
-		answerIndex := rand.Intn(3)
-		debug("%d %d\n", successIndex, answerIndex)
-		if answerIndex == successIndex {
-			successCount++
-		} else {
-			failCount++
-		}
+// play plays one game until the second choice: hides the car, makes the first answer and lets the host open
+// doors. Returns the car index, the first answer, the doors left to switch to (none if the host does not offer
+// to switch) and the condition of the game.
+func play(rnd *rand.Rand, host Host) (successIndex int, answerIndex int, switchIndexes []int, condition string) {
+	successIndex = rnd.Intn(*doors)
+	debug("Car: %d\n", successIndex)
+	answerIndex = rnd.Intn(*doors)
+	debug("First answer: %d\n", answerIndex)
+	openedIndexes, condition := host.Open(rnd, successIndex, answerIndex)
+	debug("Opened: %v (%s)\n", openedIndexes, condition)
+	if len(openedIndexes) > 0 {
+		switchIndexes = filter(otherIndexes(answerIndex), func(v int) bool { return !slices.Contains(openedIndexes, v) })
 	}
+	debug("Second choose: %d or %v\n", answerIndex, switchIndexes)
+	return successIndex, answerIndex, switchIndexes, condition
 }
 
-func answerNotChanged() {
-	for i := 0; i < N; i++ {
-		var doors [3]bool
-		allIndexes := []int{0, 1, 2}
-		successIndex := rand.Intn(len(doors))
-		doors[successIndex] = true
-		debug("doors: %v\n", doors)
-		answerIndex := rand.Intn(len(doors))
-		debug("First answer: %d\n", answerIndex)
-		otherIndexes := filter(allIndexes, func(v int) bool { return v != answerIndex })
-		otherFailIndexes := filter(otherIndexes, func(v int) bool { return v != successIndex })
-		randFailIndex := otherFailIndexes[rand.Intn(len(otherFailIndexes))]
-		otherIndexesExcludingOneFail := filter(otherIndexes, func(v int) bool { return v != randFailIndex })
-		secondChooseIndexes := make([]int, 0, 2)
-		secondChooseIndexes = append(secondChooseIndexes, answerIndex)
-		secondChooseIndexes = append(secondChooseIndexes, otherIndexesExcludingOneFail...)
-		debug("Second choose: %v\n", secondChooseIndexes)
-		answer2Index := answerIndex
-		debug("Second answer: %d\n", answer2Index)
-		if answer2Index == successIndex {
-			debug("Success\n")
-			successCount++
-		} else {
-			debug("Fail\n")
-			failCount++
-		}
+func result(answer2Index, successIndex int) int {
+	debug("Second answer: %d\n", answer2Index)
+	if answer2Index == successIndex {
+		debug("Success\n")
+		return 1
+	}
+	debug("Fail\n")
+	return 0
+}
+
+// stats counts games and successes.
+type stats struct {
+	games        int
+	successCount int
+}
+
+func (s *stats) add(success int) {
+	s.games++
+	s.successCount += success
+}
+
+// strategy plays one game with the host and returns 1 on success, 0 on fail, and the game condition.
+type strategy func(rnd *rand.Rand, host Host) (int, string)
+
+// noSimulation picks a random door without the host.
+func noSimulation(rnd *rand.Rand, host Host) (int, string) {
+	successIndex := rnd.Intn(*doors)
+	answerIndex := rnd.Intn(*doors)
+	debug("%d %d\n", successIndex, answerIndex)
+	return result(answerIndex, successIndex), "no host"
+}
+
+// answerNotChanged stays with the first answer.
+func answerNotChanged(rnd *rand.Rand, host Host) (int, string) {
+	successIndex, answerIndex, _, condition := play(rnd, host)
+	return result(answerIndex, successIndex), condition
+}
+
+// answerChanged switches to a random door of the remaining closed ones if the host offers to switch.
+func answerChanged(rnd *rand.Rand, host Host) (int, string) {
+	successIndex, answerIndex, switchIndexes, condition := play(rnd, host)
+	answer2Index := answerIndex
+	if len(switchIndexes) > 0 {
+		answer2Index = switchIndexes[rnd.Intn(len(switchIndexes))]
 	}
+	return result(answer2Index, successIndex), condition
 }
 
-func answerChanged() {
-	for i := 0; i < N; i++ {
-		var doors [3]bool
-		allIndexes := []int{0, 1, 2}
-		successIndex := rand.Intn(len(doors))
-		doors[successIndex] = true
-		debug("doors: %v\n", doors)
-		answerIndex := rand.Intn(len(doors))
-		debug("First answer: %d\n", answerIndex)
-		otherIndexes := filter(allIndexes, func(v int) bool { return v != answerIndex })
-		otherFailIndexes := filter(otherIndexes, func(v int) bool { return v != successIndex })
-		randFailIndex := otherFailIndexes[rand.Intn(len(otherFailIndexes))]
-		otherIndexesExcludingOneFail := filter(otherIndexes, func(v int) bool { return v != randFailIndex })
-		secondChooseIndexes := make([]int, 0, 2)
-		secondChooseIndexes = append(secondChooseIndexes, answerIndex)
-		secondChooseIndexes = append(secondChooseIndexes, otherIndexesExcludingOneFail...)
-		debug("Second choose: %v\n", secondChooseIndexes)
-		var answer2Index int
-		for _, i := range secondChooseIndexes {
-			if i != answerIndex {
-				answer2Index = i
-				break
+var strategies = []struct {
+	key      string
+	name     string
+	play     strategy
+	expected func() float64 // with the standard host
+}{
+	{"random", "No simulation", noSimulation, expectedNotChanged},
+	{"stay", "Answer not changed", answerNotChanged, expectedNotChanged},
+	{"switch", "Answer changed", answerChanged, expectedChanged},
+}
+
+// simulate plays trials games in parallel and returns the statistics by game condition and the cumulative
+// number of successes at the checkpoints (trial counts in increasing order).
+func simulate(play strategy, host Host, checkpoints []int) (map[string]*stats, []int) {
+	chunks := (*trials + chunkSize - 1) / chunkSize
+	// Each chunk is played by one worker, so the workers write to different elements.
+	chunkSuccesses := make([]int, chunks)
+	checkpointSuccesses := make([]int, len(checkpoints)) // successes within the chunk of the checkpoint
+	jobs := make(chan int)
+	results := make(chan map[string]*stats)
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			byCondition := make(map[string]*stats)
+			for chunk := range jobs {
+				rnd := rand.New(rand.NewSource(chunkSeed(*seed, chunk)))
+				k, _ := slices.BinarySearch(checkpoints, chunk*chunkSize+1)
+				for i := chunk * chunkSize; i < min((chunk+1)*chunkSize, *trials); i++ {
+					success, condition := play(rnd, host)
+					if byCondition[condition] == nil {
+						byCondition[condition] = &stats{}
+					}
+					byCondition[condition].add(success)
+					chunkSuccesses[chunk] += success
+					if k < len(checkpoints) && checkpoints[k] == i+1 {
+						checkpointSuccesses[k] = chunkSuccesses[chunk]
+						k++
+					}
+				}
 			}
+			results <- byCondition
+		}()
+	}
+	go func() {
+		for chunk := 0; chunk < chunks; chunk++ {
+			jobs <- chunk
 		}
-		debug("Second answer: %d\n", answer2Index)
-		if answer2Index == successIndex {
-			debug("Success\n")
-			successCount++
-		} else {
-			debug("Fail\n")
-			failCount++
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	total := make(map[string]*stats)
+	for byCondition := range results {
+		for condition, s := range byCondition {
+			if total[condition] == nil {
+				total[condition] = &stats{}
+			}
+			total[condition].games += s.games
+			total[condition].successCount += s.successCount
 		}
 	}
+
+	cumulative := make([]int, len(checkpoints))
+	before, chunk := 0, 0
+	for k, t := range checkpoints {
+		for ; chunk < (t-1)/chunkSize; chunk++ {
+			before += chunkSuccesses[chunk]
+		}
+		cumulative[k] = before + checkpointSuccesses[k]
+	}
+	return total, cumulative
+}
+
+// chunkSeed mixes the seed and the chunk number with SplitMix64, so that chunks of neighbouring seeds do not
+// share random streams as they would with seed+chunk.
+func chunkSeed(seed int64, chunk int) int64 {
+	z := uint64(seed) ^ uint64(chunk)*0x9E3779B97F4A7C15
+	z += 0x9E3779B97F4A7C15
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	return int64(z ^ z>>31)
+}
+
+// expectedNotChanged returns the probability to win staying with the first answer.
+func expectedNotChanged() float64 {
+	return 1 / float64(*doors)
+}
+
+// expectedChanged returns the probability to win switching: the car is behind one of the other doors
+// with probability (doors-1)/doors and then behind the chosen one of doors-opened-1 remaining doors.
+func expectedChanged() float64 {
+	return float64(*doors-1) / float64(*doors) / float64(*doors-*opened-1)
+}
+
+// confidenceInterval returns Wilson score interval of the success ratio.
+func confidenceInterval(successCount, n int) (float64, float64) {
+	p := float64(successCount) / float64(n)
+	z2n := Z * Z / float64(n)
+	center := (p + z2n/2) / (1 + z2n)
+	halfWidth := Z / (1 + z2n) * math.Sqrt(p*(1-p)/float64(n)+z2n/float64(4*n))
+	return max(0, center-halfWidth), min(1, center+halfWidth)
+}
+
+// selected returns indexes of items whose keys are listed in the comma-separated list or all of them.
+func selected(list string, keys []string) ([]int, error) {
+	if list == "all" {
+		indexes := make([]int, len(keys))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+	var indexes []int
+	for _, key := range strings.Split(list, ",") {
+		i := slices.Index(keys, strings.TrimSpace(key))
+		if i < 0 {
+			return nil, fmt.Errorf("unknown %q, must be one of %s or all", key, strings.Join(keys, ", "))
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: "+format+"\n", args...)
+	os.Exit(1)
 }
 
 func main() {
-	answerChanged()
+	flag.Parse()
+	if *doors < 3 || *opened < 1 || *opened > *doors-2 {
+		fail("need -doors >= 3 and 1 <= -opened <= doors-2, got -doors %d, -opened %d", *doors, *opened)
+	}
+	if *trials < 1 || *workers < 1 {
+		fail("-n and -workers must be positive")
+	}
+	if *debugFlag {
+		*workers = 1
+	}
+	seedGiven := false
+	flag.Visit(func(f *flag.Flag) { seedGiven = seedGiven || f.Name == "seed" })
+	if !seedGiven {
+		*seed = time.Now().UnixNano()
+	}
+
+	strategyKeys := make([]string, len(strategies))
+	for i, s := range strategies {
+		strategyKeys[i] = s.key
+	}
+	selectedStrategies, err := selected(*strategyNames, strategyKeys)
+	if err != nil {
+		fail("-strategy: %v", err)
+	}
+	hostKeys := make([]string, len(hosts))
+	for i, h := range hosts {
+		hostKeys[i] = h.key
+	}
+	selectedHosts, err := selected(*hostNames, hostKeys)
+	if err != nil {
+		fail("-host: %v", err)
+	}
+
+	if *playFlag {
+		hostIndex := 0
+		if *hostNames != "all" {
+			if len(selectedHosts) != 1 {
+				fail("-play needs one host")
+			}
+			hostIndex = selectedHosts[0]
+		}
+		game(os.Stdin, os.Stdout, rand.New(rand.NewSource(*seed)), hosts[hostIndex].host, hosts[hostIndex].name)
+		return
+	}
 
-	fmt.Println()
-	fmt.Printf("Success: %d\n", successCount)
-	fmt.Printf("Fail: %d\n", failCount)
-	fmt.Printf("Ratio: %.4f\n", float64(successCount)/float64(N))
+	var points []int
+	var allSeries []series
+	if *csvFile != "" || *svgFile != "" {
+		points = checkpoints(*trials)
+	}
+
+	fmt.Printf("Doors: %d, opened by host: %d, trials: %d per host and strategy, seed: %d\n", *doors, *opened, *trials, *seed)
+	for _, i := range selectedHosts {
+		h := hosts[i]
+		fmt.Printf("\nHost: %s\n", h.name)
+		fmt.Printf("%-20s %-24s %10s %10s %8s %8s %21s\n", "Strategy", "Condition", "Games", "Success", "Share", "Ratio", "95% CI")
+		for _, j := range selectedStrategies {
+			strategy := strategies[j]
+			byCondition, successes := simulate(strategy.play, h.host, points)
+			if points != nil {
+				s := series{host: h.name, strategy: strategy.name, trials: points, successes: successes}
+				if _, ok := h.host.(standardHost); ok {
+					s.expected = strategy.expected()
+				}
+				allSeries = append(allSeries, s)
+			}
+			conditions := make([]string, 0, len(byCondition))
+			for condition := range byCondition {
+				conditions = append(conditions, condition)
+			}
+			slices.Sort(conditions)
+			for _, condition := range conditions {
+				s := byCondition[condition]
+				low, high := confidenceInterval(s.successCount, s.games)
+				fmt.Printf("%-20s %-24s %10d %10d %8.4f %8.4f   [%.4f, %.4f]",
+					strategy.name, condition, s.games, s.successCount, float64(s.games)/float64(*trials), float64(s.successCount)/float64(s.games), low, high)
+				if _, ok := h.host.(standardHost); ok {
+					fmt.Printf("   expected %.4f", strategy.expected())
+				}
+				fmt.Println()
+			}
+		}
+	}
+
+	if *csvFile != "" {
+		writeFile(*csvFile, func(w io.Writer) error { return writeCsv(w, allSeries) })
+	}
+	if *svgFile != "" {
+		writeFile(*svgFile, func(w io.Writer) error { return writeSvg(w, allSeries, *trials) })
+	}
+}
+
+func writeFile(path string, write func(io.Writer) error) {
+	file, err := os.Create(path)
+	if err != nil {
+		fail("%v", err)
+	}
+	if err := write(file); err != nil {
+		fail("%s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		fail("%v", err)
+	}
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// TestSimulateWorkers checks that results depend only on the seed, not on the number of workers, and that
+// the cumulative successes at the last checkpoint add up to the total.
+func TestSimulateWorkers(t *testing.T) {
+	*seed = 5
+	*trials = 3*chunkSize + 123 // the last chunk is incomplete
+	points := checkpoints(*trials)
+	for _, h := range hosts {
+		for _, s := range strategies {
+			*workers = 1
+			want, wantCumulative := simulate(s.play, h.host, points)
+			*workers = 7
+			got, gotCumulative := simulate(s.play, h.host, points)
+
+			if !maps.EqualFunc(got, want, func(a, b *stats) bool { return *a == *b }) {
+				t.Errorf("%s, %s: statistics with 7 workers differ from 1 worker", h.key, s.key)
+			}
+			if !slices.Equal(gotCumulative, wantCumulative) {
+				t.Errorf("%s, %s: cumulative successes with 7 workers differ from 1 worker", h.key, s.key)
+			}
+			total := 0
+			for _, st := range want {
+				total += st.successCount
+			}
+			if last := wantCumulative[len(wantCumulative)-1]; last != total {
+				t.Errorf("%s, %s: cumulative successes at %d trials = %d, want %d", h.key, s.key, *trials, last, total)
+			}
+			if !slices.IsSorted(wantCumulative) {
+				t.Errorf("%s, %s: cumulative successes decrease: %v", h.key, s.key, wantCumulative)
+			}
+		}
+	}
+}
+
+// TestChunkSeed checks that chunks of neighbouring seeds get different generators.
+func TestChunkSeed(t *testing.T) {
+	for chunk := 0; chunk < 100; chunk++ {
+		if chunkSeed(1, chunk+1) == chunkSeed(2, chunk) {
+			t.Errorf("chunk %d of seed 1 has the same seed as chunk %d of seed 2", chunk+1, chunk)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Интервал обновления строки прогресса.
+const progressInterval = 500 * time.Millisecond
+
+// progress печатает количество обработанных выборок, прошедшее время и оценку оставшегося времени.
+type progress struct {
+	w        io.Writer
+	total    int // 0, если количество выборок заранее неизвестно
+	done     atomic.Int64
+	start    time.Time
+	stop     chan struct{}
+	finished chan struct{}
+}
+
+func newProgress(w io.Writer, total int) *progress {
+	p := &progress{w: w, total: total, start: time.Now(), stop: make(chan struct{}), finished: make(chan struct{})}
+	go func() {
+		defer close(p.finished)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.print()
+			case <-p.stop:
+				p.print()
+				fmt.Fprintln(p.w)
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// Inc отмечает обработку одной выборки. Безопасен для вызова из нескольких горутин.
+func (p *progress) Inc() {
+	p.done.Add(1)
+}
+
+// Done возвращает количество обработанных выборок.
+func (p *progress) Done() int {
+	return int(p.done.Load())
+}
+
+// Stop печатает последнюю строку прогресса и останавливает обновление.
+func (p *progress) Stop() {
+	close(p.stop)
+	<-p.finished
+}
+
+func (p *progress) print() {
+	done := p.Done()
+	elapsed := time.Since(p.start)
+	if p.total == 0 || done == 0 {
+		fmt.Fprintf(p.w, "\rОбработано %d, прошло %s    ", done, elapsed.Round(time.Second))
+		return
+	}
+	eta := time.Duration(float64(elapsed) / float64(done) * float64(p.total-done))
+	fmt.Fprintf(p.w, "\rОбработано %d из %d (%.1f%%), прошло %s, осталось ~%s    ",
+		done, p.total, float64(done)*100/float64(p.total), elapsed.Round(time.Second), eta.Round(time.Second))
+}
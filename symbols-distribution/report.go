@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Ширина гистограммы в символах.
+const histogramWidth = 50
+
+// Bin - строка гистограммы.
+type Bin struct {
+	Symbol   string  `json:"symbol"`
+	Observed int     `json:"observed"`
+	Expected float64 `json:"expected"`
+	Z        float64 `json:"z"` // отклонение от ожидаемой частоты в стандартных отклонениях
+}
+
+// Test - результат статистической проверки.
+type Test struct {
+	Name      string  `json:"name"`
+	Statistic float64 `json:"statistic"`
+	DF        int     `json:"df,omitempty"`
+	P         float64 `json:"p"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+}
+
+// Report - отчёт о распределении символов.
+type Report struct {
+	Samples    int     `json:"samples"`
+	Symbols    int     `json:"symbols"`
+	Unexpected int     `json:"unexpected"` // символы вне алфавита
+	Histogram  []Bin   `json:"histogram"`
+	Entropy    Entropy `json:"entropy"`
+	// Наиболее отклоняющиеся от ожидаемой частоты символы.
+	Bias      []Bin `json:"bias"`
+	Positions struct {
+		Count       int     `json:"count"`
+		Biased      int     `json:"biased"` // позиций с p < alpha
+		MinP        float64 `json:"min_p"`
+		MinPosition int     `json:"min_position"`
+	} `json:"positions"`
+	Correlation float64 `json:"correlation"`
+	Tests       []Test  `json:"tests"`
+	Passed      bool    `json:"passed"`
+}
+
+// Entropy - оценки энтропии одного символа в битах.
+type Entropy struct {
+	Expected float64 `json:"expected"` // энтропия Шеннона ожидаемого распределения
+	Observed float64 `json:"observed"` // энтропия Шеннона наблюдаемого распределения
+	Min      float64 `json:"min"`      // минимальная энтропия наблюдаемого распределения
+	Max      float64 `json:"max"`      // log2 размера алфавита
+}
+
+// newReport составляет отчёт по собранным частотам. probs - ожидаемые вероятности символов алфавита.
+func newReport(c *symbolsCounter, alphabet []byte, probs []float64, alpha float64) *Report {
+	r := &Report{Samples: c.samples, Unexpected: c.unexpected}
+	total := 0
+	for _, s := range alphabet {
+		total += c.v[s]
+	}
+	r.Symbols = total
+
+	observed := make([]int, len(alphabet))
+	expected := make([]float64, len(alphabet))
+	maxProb := 0.0
+	for i, s := range alphabet {
+		observed[i] = c.v[s]
+		expected[i] = probs[i] * float64(total)
+		z := 0.0 // символ с вероятностью 0 или 1 не может отклоняться, а NaN нельзя записать в JSON
+		if expected[i] > 0 && probs[i] < 1 {
+			z = (float64(observed[i]) - expected[i]) / math.Sqrt(expected[i]*(1-probs[i]))
+		}
+		r.Histogram = append(r.Histogram, Bin{string(s), observed[i], expected[i], z})
+
+		if probs[i] > 0 {
+			r.Entropy.Expected -= probs[i] * math.Log2(probs[i])
+		}
+		if observed[i] > 0 {
+			p := float64(observed[i]) / float64(total)
+			r.Entropy.Observed -= p * math.Log2(p)
+			maxProb = math.Max(maxProb, p)
+		}
+	}
+	r.Entropy.Max = math.Log2(float64(len(alphabet)))
+	if maxProb > 0 {
+		r.Entropy.Min = -math.Log2(maxProb)
+	}
+
+	r.Bias = append([]Bin(nil), r.Histogram...)
+	sort.SliceStable(r.Bias, func(i, j int) bool {
+		return math.Abs(r.Bias[i].Z) > math.Abs(r.Bias[j].Z)
+	})
+	r.Bias = r.Bias[:min(len(r.Bias), 5)]
+
+	stat, df := chiSquare(observed, expected)
+	r.addTest("Распределение символов (хи-квадрат)", stat, df, chiSquarePValue(stat, df), alpha)
+
+	// Для позиций используется поправка Бонферрони, так как проверок столько, сколько позиций.
+	r.Positions.Count = len(c.positions)
+	r.Positions.MinP = 1
+	for i, counts := range c.positions {
+		positionTotal := 0
+		for j, s := range alphabet {
+			observed[j] = counts[s]
+			positionTotal += counts[s]
+		}
+		for j := range alphabet {
+			expected[j] = probs[j] * float64(positionTotal)
+		}
+		stat, df := chiSquare(observed, expected)
+		p := chiSquarePValue(stat, df)
+		if p < alpha {
+			r.Positions.Biased++
+		}
+		if p < r.Positions.MinP {
+			r.Positions.MinP, r.Positions.MinPosition = p, i
+		}
+	}
+	if r.Positions.Count > 0 {
+		r.addTest("Распределение по позициям (с поправкой Бонферрони)", float64(r.Positions.Biased), 0, r.Positions.MinP, alpha/float64(r.Positions.Count))
+	}
+
+	if c.pairs > 1 {
+		correlation, ok := c.Correlation()
+		r.Correlation = correlation
+		z := r.Correlation * math.Sqrt(c.pairs)
+		p := normalPValue(z)
+		if !ok {
+			p = 0 // поток из одного повторяющегося символа заведомо не случайный
+		}
+		r.addTest("Независимость соседних символов (корреляция)", z, 0, p, alpha)
+	}
+
+	// Без символов алфавита проверкам нечего проверять, а символы вне алфавита означают, что генератор
+	// выдаёт не то, что ожидается, поэтому в обоих случаях отчёт не проходит.
+	r.Passed = r.Symbols > 0 && r.Unexpected == 0
+	for _, t := range r.Tests {
+		r.Passed = r.Passed && t.Passed
+	}
+	return r
+}
+
+func (r *Report) addTest(name string, statistic float64, df int, p float64, threshold float64) {
+	r.Tests = append(r.Tests, Test{name, statistic, df, p, threshold, p >= threshold})
+}
+
+// WriteJSON печатает отчёт в формате JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText печатает отчёт в текстовом виде с гистограммой.
+func (r *Report) WriteText(w io.Writer) {
+	maxCount := 1
+	for _, bin := range r.Histogram {
+		maxCount = max(maxCount, bin.Observed)
+	}
+	for _, bin := range r.Histogram {
+		bar := strings.Repeat("#", bin.Observed*histogramWidth/maxCount)
+		fmt.Fprintf(w, "%s: %d (ожидается %.0f, z = %+.2f) %s\n", bin.Symbol, bin.Observed, bin.Expected, bin.Z, bar)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Выборок: %d, символов: %d, символов вне алфавита: %d\n", r.Samples, r.Symbols, r.Unexpected)
+	fmt.Fprintf(w, "Энтропия символа: ожидаемая %.4f бит, наблюдаемая %.4f бит, минимальная %.4f бит, максимум %.4f бит\n",
+		r.Entropy.Expected, r.Entropy.Observed, r.Entropy.Min, r.Entropy.Max)
+	fmt.Fprint(w, "Наибольшие отклонения:")
+	for _, bin := range r.Bias {
+		fmt.Fprintf(w, " %q (z = %+.2f)", bin.Symbol, bin.Z)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Позиций с p < alpha: %d из %d, наименьшее p на позиции %d\n",
+		r.Positions.Biased, r.Positions.Count, r.Positions.MinPosition)
+	fmt.Fprintf(w, "Корреляция соседних символов: %.6f\n", r.Correlation)
+	fmt.Fprintln(w)
+
+	if r.Symbols == 0 {
+		fmt.Fprintln(w, "Символов алфавита нет, проверка НЕ ПРОЙДЕНА")
+	}
+	if r.Unexpected > 0 {
+		fmt.Fprintf(w, "Символов вне алфавита: %d, проверка НЕ ПРОЙДЕНА\n", r.Unexpected)
+	}
+	for _, t := range r.Tests {
+		result := "пройдена"
+		if !t.Passed {
+			result = "НЕ ПРОЙДЕНА"
+		}
+		if t.DF > 0 {
+			fmt.Fprintf(w, "%s: статистика %.2f, степеней свободы %d, p = %.4g, проверка %s\n", t.Name, t.Statistic, t.DF, t.P, result)
+		} else {
+			fmt.Fprintf(w, "%s: p = %.4g (порог %.4g), проверка %s\n", t.Name, t.P, t.Threshold, result)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import "math"
+
+// chiSquare возвращает статистику хи-квадрат для наблюдаемых частот observed и ожидаемых expected.
+// Ячейки с нулевой ожидаемой частотой пропускаются. Второе значение - число степеней свободы.
+func chiSquare(observed []int, expected []float64) (float64, int) {
+	stat := 0.0
+	cells := 0
+	for i, e := range expected {
+		if e == 0 {
+			continue
+		}
+		d := float64(observed[i]) - e
+		stat += d * d / e
+		cells++
+	}
+	return stat, cells - 1
+}
+
+// chiSquarePValue возвращает вероятность получить статистику хи-квадрат не меньше stat
+// при df степенях свободы, то есть Q(df/2, stat/2).
+func chiSquarePValue(stat float64, df int) float64 {
+	if df < 1 {
+		return 1
+	}
+	return gammaQ(float64(df)/2, stat/2)
+}
+
+// normalPValue возвращает двустороннее p-значение для стандартной нормальной величины z.
+func normalPValue(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// gammaQ - регуляризованная верхняя неполная гамма-функция Q(a, x) = 1 - P(a, x).
+// При x < a+1 используется ряд для P(a, x), иначе цепная дробь для Q(a, x) (Numerical Recipes, 6.2).
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < 10000; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*prefix
+	}
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 10000; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Квантили распределения хи-квадрат из таблиц: P(X >= stat) = p при df степенях свободы.
+func TestChiSquarePValue(t *testing.T) {
+	tests := []struct {
+		stat float64
+		df   int
+		p    float64
+	}{
+		{3.841, 1, 0.05},
+		{6.635, 1, 0.01},
+		{5.991, 2, 0.05},
+		{15.086, 5, 0.01},
+		{18.307, 10, 0.05},
+		{2.558, 10, 0.99},
+		{43.773, 30, 0.05},
+		{124.342, 100, 0.05},
+		{0, 3, 1},
+	}
+	for _, test := range tests {
+		if p := chiSquarePValue(test.stat, test.df); math.Abs(p-test.p) > 1e-4 {
+			t.Errorf("chiSquarePValue(%v, %d) = %.6f, want %v", test.stat, test.df, p, test.p)
+		}
+	}
+	if p := normalPValue(1.959964); math.Abs(p-0.05) > 1e-6 {
+		t.Errorf("normalPValue(1.96) = %.6f, want 0.05", p)
+	}
+}
+
+func TestExpectedProbs(t *testing.T) {
+	probs, err := expectedProbs([]byte("ab"), "1,3")
+	if err != nil || probs[0] != 0.25 || probs[1] != 0.75 {
+		t.Errorf("expectedProbs(ab, 1,3) = %v, %v", probs, err)
+	}
+	// 256 = 2*95 + 66: первые 66 символов выпадают с вероятностью 3/256, остальные 2/256.
+	probs, err = expectedProbs(make([]byte, 95), "modulo")
+	if err != nil || probs[0] != 3.0/256 || probs[65] != 3.0/256 || probs[66] != 2.0/256 {
+		t.Errorf("expectedProbs(95, modulo) = %v, %v", probs, err)
+	}
+	for _, dist := range []string{"1", "1,x", "1,-1", "0,0"} {
+		if _, err := expectedProbs([]byte("ab"), dist); err == nil {
+			t.Errorf("expectedProbs(ab, %s) returned no error", dist)
+		}
+	}
+}
+
+// counter собирает выборки длиной length из алфавита с помощью symbol.
+func counter(alphabet string, samples, length int, symbol func(i int) byte) *symbolsCounter {
+	c := newSymbolsCounter([]byte(alphabet))
+	sample := make([]byte, length)
+	for i := 0; i < samples; i++ {
+		for j := range sample {
+			sample[j] = symbol(i*length + j)
+		}
+		c.Add(sample)
+	}
+	return c
+}
+
+func TestReport(t *testing.T) {
+	const alphabet = "abcd"
+	rnd := rand.New(rand.NewSource(1))
+	uniform := []float64{0.25, 0.25, 0.25, 0.25}
+	tests := []struct {
+		name   string
+		c      *symbolsCounter
+		passed bool
+	}{
+		{"random", counter(alphabet, 10000, 8, func(int) byte { return alphabet[rnd.Intn(4)] }), true},
+		{"biased", counter(alphabet, 10000, 8, func(int) byte { return alphabet[rnd.Intn(4)%3] }), false},
+		{"position", counter(alphabet, 10000, 8, func(i int) byte {
+			if i%8 == 0 {
+				return 'a'
+			}
+			return alphabet[rnd.Intn(4)]
+		}), false},
+		{"sequence", counter(alphabet, 10000, 8, func(i int) byte { return alphabet[i/8%4] }), false},
+		{"empty", newSymbolsCounter([]byte(alphabet)), false},
+		{"unexpected", counter(alphabet, 10, 3, func(int) byte { return 'z' }), false},
+	}
+	for _, test := range tests {
+		r := newReport(test.c, []byte(alphabet), uniform, 0.001)
+		if r.Passed != test.passed {
+			t.Errorf("%s: Passed = %v, want %v, tests %+v", test.name, r.Passed, test.passed, r.Tests)
+		}
+	}
+}
+
+// TestReportConstant проверяет, что для постоянного потока (нулевая дисперсия) в отчёте нет NaN.
+func TestReportConstant(t *testing.T) {
+	c := counter("ab", 2, 4, func(int) byte { return 'a' })
+	r := newReport(c, []byte("ab"), []float64{0.5, 0.5}, 0.001)
+	if r.Correlation != 0 || r.Passed {
+		t.Errorf("Correlation = %v, Passed = %v, want 0, false", r.Correlation, r.Passed)
+	}
+	if err := r.WriteJSON(io.Discard); err != nil {
+		t.Error(err)
+	}
+	r = newReport(c, []byte("ab"), []float64{1, 0}, 0.001)
+	if err := r.WriteJSON(io.Discard); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,71 +1,344 @@
 /*
-Изучаем распределение символов в паролях, выдаваемых утилитой genpass.
-Частота появление для символов неодинакова, потому что символов 95, а байт - 256.
+Изучаем распределение символов в паролях, выдаваемых утилитой genpass или любым другим генератором.
+Частота появление для символов genpass неодинакова, потому что символов 95, а байт - 256.
 В связи с этим ожидаем, что частота появления символов "$%&'()*+,-./:;<=>?@[\\]^_`{|}~" 2*n,
 а остальных символов - 3*n, где n = N/4.
+
+Выборки берутся из одного источника:
+  - genpass (по умолчанию) - пароли вычисляются в процессе с помощью пакета genpass/derive по фразам 0, 1, ...;
+  - -cmd - команда, где {} заменяется номером выборки, например 'genpass -c=64 {}';
+  - -stdin - стандартный ввод.
+
+Каждая непустая строка вывода - отдельная выборка (пароль).
+
+Распределение проверяется критерием хи-квадрат в целом и для каждой позиции в выборке, а независимость
+соседних символов - по коэффициенту корреляции их индексов в алфавите. Если хотя бы одна проверка не
+пройдена на уровне значимости -alpha, программа завершается с кодом 1.
 */
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"os"
 	"os/exec"
+	"os/signal"
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+
+	"go-scripts/genpass/derive"
 )
 
 const (
-	N = 1000000 // количество вызовов genpass
+	nDesc        = "количество выборок, по умолчанию 1000000; для -stdin читаются все строки"
+	jDesc        = "количество обработчиков, по умолчанию число процессоров"
+	cmdDesc      = "команда-генератор, {} заменяется номером выборки"
+	stdinDesc    = "читать выборки из стандартного ввода"
+	lengthDesc   = "длина пароля genpass, по умолчанию 64"
+	alphabetDesc = "ожидаемый алфавит, по умолчанию набор genpass"
+	distDesc     = "ожидаемое распределение: uniform, modulo (байт % размер алфавита, как в genpass) или веса через запятую; по умолчанию modulo для genpass, иначе uniform"
+	alphaDesc    = "уровень значимости проверок, по умолчанию 0.001"
+	formatDesc   = "формат отчёта: text (по умолчанию) или json"
 )
 
 var (
-	symbols     = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~")
-	processChan = make(chan bool, 100) // Максимум 100 одновременно запущенных процессов
+	symbols = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~")
 )
 
+// symbolsCounter собирает частоты символов. У каждого обработчика свой счётчик, поэтому блокировки
+// не нужны; в конце счётчики объединяются методом Merge.
 type symbolsCounter struct {
-	v   map[byte]int
-	mux sync.Mutex
+	v          [256]int
+	positions  [][256]int // частоты символов на каждой позиции выборки
+	samples    int
+	unexpected int // символы вне алфавита
+	// Суммы для коэффициента корреляции индексов соседних символов x и y.
+	pairs, sumX, sumY, sumXY, sumXX, sumYY float64
+	index                                  *[256]int // индекс символа в алфавите или -1
+}
+
+func newSymbolsCounter(alphabet []byte) *symbolsCounter {
+	index := new([256]int)
+	for i := range index {
+		index[i] = -1
+	}
+	for i, s := range alphabet {
+		index[s] = i
+	}
+	return &symbolsCounter{index: index}
+}
+
+// Worker возвращает пустой счётчик с тем же алфавитом для отдельного обработчика.
+func (c *symbolsCounter) Worker() *symbolsCounter {
+	return &symbolsCounter{index: c.index}
+}
+
+// Merge добавляет к счётчику частоты счётчика other.
+func (c *symbolsCounter) Merge(other *symbolsCounter) {
+	for b, count := range other.v {
+		c.v[b] += count
+	}
+	for len(c.positions) < len(other.positions) {
+		c.positions = append(c.positions, [256]int{})
+	}
+	for i := range other.positions {
+		for b, count := range other.positions[i] {
+			c.positions[i][b] += count
+		}
+	}
+	c.samples += other.samples
+	c.unexpected += other.unexpected
+	c.pairs += other.pairs
+	c.sumX += other.sumX
+	c.sumY += other.sumY
+	c.sumXY += other.sumXY
+	c.sumXX += other.sumXX
+	c.sumYY += other.sumYY
+}
+
+func (c *symbolsCounter) Add(sample []byte) {
+	c.samples++
+	for len(c.positions) < len(sample) {
+		c.positions = append(c.positions, [256]int{})
+	}
+	for i, b := range sample {
+		c.v[b]++
+		c.positions[i][b]++
+		if c.index[b] == -1 {
+			c.unexpected++
+			continue
+		}
+		if i > 0 && c.index[sample[i-1]] != -1 {
+			x, y := float64(c.index[sample[i-1]]), float64(c.index[b])
+			c.pairs++
+			c.sumX += x
+			c.sumY += y
+			c.sumXY += x * y
+			c.sumXX += x * x
+			c.sumYY += y * y
+		}
+	}
 }
 
-func (c *symbolsCounter) Inc(key byte) {
-	c.mux.Lock()
-	c.v[key]++
-	c.mux.Unlock()
+// AddLines добавляет каждую непустую строку out как отдельную выборку.
+func (c *symbolsCounter) AddLines(out []byte) {
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) > 0 {
+			c.Add(line)
+		}
+	}
 }
-func (c *symbolsCounter) Value(key byte) int {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-	return c.v[key]
+
+// Correlation возвращает коэффициент корреляции Пирсона индексов соседних символов. Если символы
+// не меняются (дисперсия нулевая), корреляция не определена: возвращается 0 и false.
+func (c *symbolsCounter) Correlation() (float64, bool) {
+	cov := c.sumXY/c.pairs - c.sumX/c.pairs*c.sumY/c.pairs
+	varX := c.sumXX/c.pairs - c.sumX/c.pairs*c.sumX/c.pairs
+	varY := c.sumYY/c.pairs - c.sumY/c.pairs*c.sumY/c.pairs
+	if varX <= 0 || varY <= 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
+
+// expectedProbs возвращает ожидаемые вероятности символов алфавита для распределения dist.
+func expectedProbs(alphabet []byte, dist string) ([]float64, error) {
+	probs := make([]float64, len(alphabet))
+	switch dist {
+	case "uniform":
+		for i := range probs {
+			probs[i] = 1 / float64(len(alphabet))
+		}
+	case "modulo":
+		if len(alphabet) > 256 {
+			return nil, fmt.Errorf("алфавит больше 256 символов")
+		}
+		for b := 0; b < 256; b++ {
+			probs[b%len(alphabet)] += 1.0 / 256
+		}
+	default:
+		weights := strings.Split(dist, ",")
+		if len(weights) != len(alphabet) {
+			return nil, fmt.Errorf("количество весов (%d) не совпадает с размером алфавита (%d)", len(weights), len(alphabet))
+		}
+		sum := 0.0
+		for i, w := range weights {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("неправильный вес %q", w)
+			}
+			probs[i] = weight
+			sum += weight
+		}
+		if sum == 0 {
+			return nil, fmt.Errorf("сумма весов равна 0")
+		}
+		for i := range probs {
+			probs[i] /= sum
+		}
+	}
+	return probs, nil
 }
 
 func main() {
-	sc := &symbolsCounter{v: make(map[byte]int)}
+	n := flag.Int("n", 1000000, nDesc)
+	j := flag.Int("j", runtime.NumCPU(), jDesc)
+	cmd := flag.String("cmd", "", cmdDesc)
+	stdin := flag.Bool("stdin", false, stdinDesc)
+	length := flag.Int("length", 64, lengthDesc)
+	alphabetArg := flag.String("alphabet", string(symbols), alphabetDesc)
+	dist := flag.String("dist", "", distDesc)
+	alpha := flag.Float64("alpha", 0.001, alphaDesc)
+	format := flag.String("format", "text", formatDesc)
+	flag.Parse()
 
-	var wg sync.WaitGroup
-	wg.Add(N)
+	if *n < 1 || *j < 1 {
+		log.Fatalf("-n и -j должны быть больше 0")
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("неизвестный формат %q", *format)
+	}
+	if *cmd != "" && *stdin {
+		log.Fatal("-cmd и -stdin нельзя указывать одновременно")
+	}
 
-	for i := 0; i < N; i++ {
-		go func(phrase string) {
-			processChan <- true
-			defer wg.Done()
+	alphabet := []byte(*alphabetArg)
+	if *dist == "" {
+		*dist = "uniform"
+		if *cmd == "" && !*stdin {
+			*dist = "modulo"
+		}
+	}
+	probs, err := expectedProbs(alphabet, *dist)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := derive.DefaultOptions()
+	opts.Length = *length
+	if _, _, err := derive.Charset(opts); err != nil {
+		log.Fatal(err)
+	}
+
+	sc := newSymbolsCounter(alphabet)
 
-			out, err := exec.Command("genpass", "-c=64", phrase).Output()
-			if err != nil {
-				log.Fatal(err)
+	// Выборки распределяются между -j обработчиками. По SIGINT новые выборки не раздаются,
+	// обработчики завершают текущие, и печатается отчёт по уже обработанным выборкам.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	jobs := make(chan []byte)
+	total := *n
+	if *stdin {
+		total = 0 // неизвестно заранее
+		go func() {
+			defer close(jobs)
+			reader := bufio.NewReader(os.Stdin)
+			for {
+				line, err := reader.ReadBytes('\n')
+				if len(line) > 0 {
+					select {
+					case jobs <- line:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					log.Fatal(err)
+				}
 			}
-			for _, b := range out {
-				sc.Inc(b)
+		}()
+	} else {
+		go func() {
+			defer close(jobs)
+			for i := 0; i < *n; i++ {
+				select {
+				case jobs <- []byte(strconv.Itoa(i)):
+				case <-ctx.Done():
+					return
+				}
 			}
+		}()
+	}
+
+	progress := newProgress(os.Stderr, total)
+	workers := make([]*symbolsCounter, *j)
+	var wg sync.WaitGroup
+	wg.Add(*j)
+	for w := range workers {
+		workers[w] = sc.Worker()
+		go func(c *symbolsCounter) {
+			defer wg.Done()
+			for {
+				var job []byte
+				var ok bool
+				select {
+				case job, ok = <-jobs:
+				case <-ctx.Done():
+				}
+				if !ok {
+					return
+				}
 
-			<-processChan
-		}(strconv.Itoa(i))
+				switch {
+				case *stdin:
+					c.AddLines(job)
+				case *cmd != "":
+					// Ctrl+C прерывает и команду, тогда её ошибка не важна: печатается отчёт по готовым выборкам.
+					out, err := exec.CommandContext(ctx, "sh", "-c", strings.ReplaceAll(*cmd, "{}", string(job))).Output()
+					if ctx.Err() != nil {
+						return
+					}
+					if err != nil {
+						log.Fatal(err)
+					}
+					c.AddLines(out)
+				default:
+					password, err := derive.Password(string(job), opts)
+					if err != nil {
+						log.Fatal(err)
+					}
+					c.Add([]byte(password))
+				}
+				progress.Inc()
+			}
+		}(workers[w])
 	}
 
 	wg.Wait()
+	progress.Stop()
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Прервано, отчёт по %d обработанным выборкам\n", progress.Done())
+	}
+	stop()
+
+	for _, c := range workers {
+		sc.Merge(c)
+	}
+
+	report := newReport(sc, alphabet, probs, *alpha)
+	if *format == "json" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
 
-	for _, s := range symbols {
-		fmt.Printf("%s: %d\n", string(s), sc.Value(s))
+	if !report.Passed {
+		os.Exit(1)
 	}
 }
 